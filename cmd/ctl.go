@@ -0,0 +1,94 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/spf13/cobra"
+	"github.com/tcpaddock/shiplot/internal/sower/ctl"
+)
+
+var (
+	ctlSocketPath string
+	ctlTuneN      int
+	ctlDropPath   string
+	ctlCancelJob  string
+)
+
+// ctlCmd represents the ctl command
+var ctlCmd = &cobra.Command{
+	Use:   "ctl",
+	Short: "Sends one status/pause/resume/tune/drop/cancel command to a running shiplot instance.",
+	Long: `
+Ctl connects to the Unix socket opened by a running "shiplot run"
+(see --control.socketPath) and sends it one command, then prints the
+response and exits.
+
+For example:
+
+$ shiplot ctl --control.socketPath /var/run/shiplot/control.sock status
+$ shiplot ctl --control.socketPath /var/run/shiplot/control.sock pause
+$ shiplot ctl --control.socketPath /var/run/shiplot/control.sock tune --n 8
+$ shiplot ctl --control.socketPath /var/run/shiplot/control.sock drop --path /mnt/disk3
+$ shiplot ctl --control.socketPath /var/run/shiplot/control.sock cancel --job plot-k32-2023.plot
+
+`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"status", "pause", "resume", "tune", "drop", "cancel"},
+	Run: func(cmd *cobra.Command, args []string) {
+		req := ctl.Request{Cmd: args[0], N: ctlTuneN, Path: ctlDropPath, Job: ctlCancelJob}
+
+		conn, err := net.Dial("unix", ctlSocketPath)
+		cobra.CheckErr(err)
+		defer conn.Close()
+
+		cobra.CheckErr(json.NewEncoder(conn).Encode(req))
+
+		var resp ctl.Response
+		cobra.CheckErr(json.NewDecoder(conn).Decode(&resp))
+
+		if !resp.OK {
+			cobra.CheckErr(fmt.Errorf("%s", resp.Error))
+		}
+
+		if resp.Stats != nil {
+			for _, t := range resp.Stats {
+				fmt.Printf("%s\t%d/%d\t%.0f B/s\tETA %s\n", t.Name, t.Written, t.Total, t.RateBps, t.ETA)
+			}
+			return
+		}
+
+		fmt.Println("ok")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ctlCmd)
+
+	ctlCmd.Flags().StringVar(&ctlSocketPath, "control.socketPath", "", "Path to the control Unix socket opened by \"shiplot run\"")
+	ctlCmd.Flags().IntVar(&ctlTuneN, "n", 0, "New worker pool size, for \"tune\"")
+	ctlCmd.Flags().StringVar(&ctlDropPath, "path", "", "Destination path to remove, for \"drop\"")
+	ctlCmd.Flags().StringVar(&ctlCancelJob, "job", "", "Transfer name to cancel, for \"cancel\"")
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tcpaddock/shiplot/internal/transport/noise"
+)
+
+var keygenOutFile string
+
+// keygenCmd represents the keygen command
+var keygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generates a Noise transport identity keypair.",
+	Long: `
+Keygen generates a new static Curve25519 identity keypair for
+the Noise transport (server.identityKey / client.identityKey).
+The private key is written to --out (0600) and the matching
+public key to --out.pub (0644); hand the .pub file to the other
+side to add to its server.authorizedKeys or client.serverKey.
+
+For example:
+
+$ shiplot keygen --out server.key
+$ shiplot keygen --out client.key
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		kp, err := noise.GenerateKeypair()
+		cobra.CheckErr(err)
+
+		err = noise.SaveKeypair(kp, keygenOutFile)
+		cobra.CheckErr(err)
+
+		fmt.Printf("Wrote private key to %s and public key to %s.pub\n", keygenOutFile, keygenOutFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(keygenCmd)
+
+	keygenCmd.Flags().StringVar(&keygenOutFile, "out", "identity.key", "Path to write the private key to (public key is written alongside it as <out>.pub)")
+}
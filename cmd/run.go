@@ -23,11 +23,18 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/tcpaddock/shiplot/internal/progress"
 	"github.com/tcpaddock/shiplot/internal/server"
+	"golang.org/x/term"
 )
 
+var progressMode string
+
 // runCmd represents the run command
 var runCmd = &cobra.Command{
 	Use:   "run",
@@ -41,17 +48,56 @@ will automatically remove destination paths that are full.
 The destination path with the most free space will be
 preferred.
 
+--progress controls how transfer progress is shown on stdout
+in addition to (or instead of) --progress.socketPath: "auto"
+picks "tty" when stdout is a terminal and "plain" otherwise,
+"tty" forces the in-place multi-line display, "plain" prints
+one line per update, and "none" disables it.
+
+--control.socketPath opens a second Unix socket accepting
+status/pause/resume/tune/drop/cancel commands from "shiplot ctl",
+so an operator can steer a run without restarting it.
+
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		s, err := server.NewServer(cfg)
 		cobra.CheckErr(err)
 
+		if err := attachProgressRenderer(s); err != nil {
+			cobra.CheckErr(err)
+		}
+
 		ctx := context.Background()
 		err = s.Start(ctx)
 		cobra.CheckErr(err)
 	},
 }
 
+// attachProgressRenderer subscribes a progress.Renderer to s's progress
+// feed according to --progress, unless it's "none".
+func attachProgressRenderer(s *server.Server) error {
+	tty := term.IsTerminal(int(os.Stdout.Fd()))
+
+	switch progressMode {
+	case "none":
+		return nil
+	case "auto":
+		// use tty as detected above
+	case "tty":
+		tty = true
+	case "plain":
+		tty = false
+	default:
+		return fmt.Errorf("unknown --progress value %q", progressMode)
+	}
+
+	r := progress.NewRenderer(os.Stdout, tty)
+	ch, _ := s.Progress().Subscribe()
+	go r.Render(ch)
+
+	return nil
+}
+
 func init() {
 	RootCmd.AddCommand(runCmd)
 
@@ -64,4 +110,11 @@ func init() {
 	runCmd.PersistentFlags().Uint16Var(&cfg.Client.ServerPort, "client.serverPort", 9080, "Server listen port (default is 9080)")
 	runCmd.PersistentFlags().StringArrayVar(&cfg.StagingPaths, "stagingPaths", nil, "Directory on fast storage used to stage plots")
 	runCmd.PersistentFlags().StringArrayVar(&cfg.DestinationPaths, "destinationPaths", nil, "Directories for final plot storage")
+	runCmd.PersistentFlags().StringVar(&cfg.Progress.SocketPath, "progress.socketPath", "", "Path to a Unix socket to stream transfer progress on (default is disabled)")
+	runCmd.PersistentFlags().StringVar(&cfg.Control.SocketPath, "control.socketPath", "", "Path to a Unix socket accepting status/pause/resume/tune/drop/cancel commands (default is disabled)")
+	runCmd.PersistentFlags().StringVar(&progressMode, "progress", "auto", "Show transfer progress on stdout: auto, tty, plain or none (default is auto)")
+	runCmd.PersistentFlags().BoolVar(&cfg.Server.ResumeEnabled, "server.resumeEnabled", true, "Allow clients to resume interrupted uploads from a partial \".tmp\" file (default is true)")
+	runCmd.PersistentFlags().DurationVar(&cfg.Server.ResumeTTL, "server.resumeTTL", 24*time.Hour, "How long an abandoned partial download is kept before the janitor deletes it (default is 24h)")
+	runCmd.PersistentFlags().Int64Var(&cfg.MaxReadBytesPerSec, "maxReadBytesPerSec", 0, "Throttle incoming transfer bytes to this many bytes/sec (default is 0, unlimited)")
+	runCmd.PersistentFlags().Int64Var(&cfg.MaxWriteBytesPerSec, "maxWriteBytesPerSec", 0, "Throttle outgoing transfer bytes to this many bytes/sec (default is 0, unlimited)")
 }
@@ -0,0 +1,77 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tcpaddock/shiplot/internal/progress"
+	"golang.org/x/term"
+)
+
+var statusSocketPath string
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Streams live transfer progress from a running shiplot instance.",
+	Long: `
+Status connects to the Unix socket opened by a running "shiplot run"
+(see --progress.socketPath) and renders every move, upload and download
+it reports as it happens: an in-place, multi-line display when stdout
+is a terminal, or one line per update otherwise.
+
+For example:
+
+$ shiplot status --progress.socketPath /var/run/shiplot/status.sock
+
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conn, err := net.Dial("unix", statusSocketPath)
+		cobra.CheckErr(err)
+		defer conn.Close()
+
+		r := progress.NewRenderer(os.Stdout, term.IsTerminal(int(os.Stdout.Fd())))
+		dec := json.NewDecoder(conn)
+		for {
+			var v progress.Vertex
+			if err := dec.Decode(&v); err != nil {
+				if err == io.EOF {
+					return
+				}
+				cobra.CheckErr(err)
+			}
+
+			r.Update(v)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringVar(&statusSocketPath, "progress.socketPath", "", "Path to the status Unix socket opened by \"shiplot run\"")
+}
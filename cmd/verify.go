@@ -0,0 +1,69 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tcpaddock/shiplot/internal/sower"
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify <path>",
+	Short: "Checks a landed plot against its BLAKE2b-256 digest sidecar.",
+	Long: `
+Verify re-hashes a plot with BLAKE2b-256 and compares it against the
+".b2" sidecar file shiplot wrote alongside it when the plot was
+shipped, catching corruption introduced after the transfer itself
+was verified.
+
+For example:
+
+$ shiplot verify /plots/plot-k32-2023-01-01.plot
+
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[0]
+
+		expected, err := sower.ReadSidecar(path)
+		cobra.CheckErr(err)
+
+		actual, err := sower.HashFile(path)
+		cobra.CheckErr(err)
+
+		if !bytes.Equal(expected[:], actual[:]) {
+			fmt.Printf("%s: digest mismatch\n", path)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s: OK\n", path)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
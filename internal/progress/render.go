@@ -0,0 +1,99 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Renderer consumes a stream of Vertex updates, from either a Writer
+// subscription in-process or a decoded status-socket feed, and draws them
+// to out. In tty mode every known vertex is redrawn in place each update;
+// otherwise each update is appended as its own line, suitable for piping.
+type Renderer struct {
+	out io.Writer
+	tty bool
+
+	mu       sync.Mutex
+	vertices map[string]Vertex
+	order    []string
+	drawn    int
+}
+
+// NewRenderer returns a Renderer that writes to out.
+func NewRenderer(out io.Writer, tty bool) *Renderer {
+	return &Renderer{out: out, tty: tty, vertices: make(map[string]Vertex)}
+}
+
+// Render consumes updates from ch until it's closed.
+func (r *Renderer) Render(ch <-chan Vertex) {
+	for v := range ch {
+		r.Update(v)
+	}
+}
+
+// Update draws a single Vertex update, for callers (like "shiplot status")
+// that decode updates one at a time off a socket rather than feeding them
+// through a channel.
+func (r *Renderer) Update(v Vertex) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.vertices[v.Name]; !ok {
+		r.order = append(r.order, v.Name)
+		sort.Strings(r.order)
+	}
+	r.vertices[v.Name] = v
+
+	if r.tty {
+		r.drawLocked()
+		return
+	}
+
+	fmt.Fprintln(r.out, formatLine(v))
+}
+
+// drawLocked redraws every known vertex in place over whatever this
+// Renderer drew last time. The caller must hold r.mu.
+func (r *Renderer) drawLocked() {
+	if r.drawn > 0 {
+		fmt.Fprintf(r.out, "\x1b[%dA", r.drawn)
+	}
+
+	for _, name := range r.order {
+		fmt.Fprintf(r.out, "\x1b[2K\r%s\n", formatLine(r.vertices[name]))
+	}
+
+	r.drawn = len(r.order)
+}
+
+func formatLine(v Vertex) string {
+	line := fmt.Sprintf("%-40s %-12s %10d/%-10d %8.2f MB/s", v.Name, v.Status, v.Written, v.Total, v.RateBps/(1<<20))
+	if v.Status == StatusError {
+		return line + " " + v.Error
+	}
+
+	return line
+}
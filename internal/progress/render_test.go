@@ -0,0 +1,78 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package progress
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRendererPlain(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, false)
+	ch := make(chan Vertex, 2)
+	ch <- Vertex{Name: "plot-k32.plot", Status: StatusTransferring, Written: 10, Total: 100}
+	ch <- Vertex{Name: "plot-k32.plot", Status: StatusDone, Written: 100, Total: 100}
+	close(ch)
+
+	// Act
+	r.Render(ch)
+
+	// Assert
+	require.Equal(t, 2, bytes.Count(buf.Bytes(), []byte("\n")))
+	require.Contains(t, buf.String(), "plot-k32.plot")
+	require.Contains(t, buf.String(), string(StatusDone))
+}
+
+func TestRendererTtyRedrawsInPlace(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, true)
+	ch := make(chan Vertex, 2)
+	ch <- Vertex{Name: "plot-k32.plot", Status: StatusQueued}
+	ch <- Vertex{Name: "plot-k32.plot", Status: StatusDone, Written: 100, Total: 100}
+	close(ch)
+
+	// Act
+	r.Render(ch)
+
+	// Assert: the second draw must move the cursor back up before redrawing.
+	require.Contains(t, buf.String(), "\x1b[1A")
+}
+
+func TestRendererErrorIncludesMessage(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	r := NewRenderer(&buf, false)
+	ch := make(chan Vertex, 1)
+	ch <- Vertex{Name: "plot-k32.plot", Status: StatusError, Error: "digest mismatch"}
+	close(ch)
+
+	// Act
+	r.Render(ch)
+
+	// Assert
+	require.Contains(t, buf.String(), "digest mismatch")
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+
+	"golang.org/x/exp/slog"
+)
+
+// SocketServer streams every Vertex published to a Writer as newline-
+// delimited JSON to each connection on a Unix socket, so `shiplot status`
+// and other scripts can watch transfer progress from outside the process.
+type SocketServer struct {
+	writer *Writer
+	path   string
+}
+
+// NewSocketServer returns a SocketServer that serves writer's updates at
+// path once Run is called.
+func NewSocketServer(writer *Writer, path string) *SocketServer {
+	return &SocketServer{writer: writer, path: path}
+}
+
+// Run listens on s.path until ctx is cancelled, streaming each connection
+// its own live feed of Vertex updates as they're published.
+func (s *SocketServer) Run(ctx context.Context) (err error) {
+	os.Remove(s.path)
+
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			slog.Default().Error("Failed to accept status connection", err)
+			continue
+		}
+
+		go s.serve(conn)
+	}
+}
+
+func (s *SocketServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	ch, cancel := s.writer.Subscribe()
+	defer cancel()
+
+	enc := json.NewEncoder(conn)
+	for v := range ch {
+		if err := enc.Encode(v); err != nil {
+			return
+		}
+	}
+}
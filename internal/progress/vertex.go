@@ -0,0 +1,54 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package progress models the lifecycle of in-flight plot transfers as a
+// stream of Vertex updates, and fans that stream out to any number of
+// consumers (an in-process TTY renderer, the status Unix socket) through a
+// Writer.
+package progress
+
+import "time"
+
+// Status is a Vertex's position in its transfer lifecycle.
+type Status string
+
+// Vertex status transitions. A transfer normally moves queued ->
+// transferring -> verifying -> done, short-circuiting to error from any
+// state it fails in.
+const (
+	StatusQueued       Status = "queued"
+	StatusTransferring Status = "transferring"
+	StatusVerifying    Status = "verifying"
+	StatusDone         Status = "done"
+	StatusError        Status = "error"
+)
+
+// Vertex is a point-in-time snapshot of a single in-flight plot transfer.
+type Vertex struct {
+	Name    string        `json:"name"`
+	Status  Status        `json:"status"`
+	Written uint64        `json:"written"`
+	Total   uint64        `json:"total"`
+	RateBps float64       `json:"rateBps"`
+	ETA     time.Duration `json:"eta"`
+	Error   string        `json:"error,omitempty"`
+}
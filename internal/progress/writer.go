@@ -0,0 +1,74 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package progress
+
+import "sync"
+
+// subscriberBuffer bounds how many unread Vertex updates a subscriber may
+// fall behind by before new ones are dropped for it.
+const subscriberBuffer = 64
+
+// Writer fans a stream of Vertex updates out to any number of subscribers,
+// e.g. the status Unix socket and an in-process TTY renderer. The zero
+// value is not usable; construct one with NewWriter.
+type Writer struct {
+	mu          sync.Mutex
+	subscribers map[chan Vertex]struct{}
+}
+
+// NewWriter returns a Writer with no subscribers.
+func NewWriter() *Writer {
+	return &Writer{subscribers: make(map[chan Vertex]struct{})}
+}
+
+// Write publishes v to every current subscriber. A subscriber that isn't
+// keeping up has this update dropped for it rather than being allowed to
+// block the producer.
+func (w *Writer) Write(v Vertex) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for ch := range w.subscribers {
+		select {
+		case ch <- v:
+		default:
+		}
+	}
+}
+
+// Subscribe registers a new subscriber and returns a channel of Vertex
+// updates along with a func to unregister it. The caller must call the
+// func exactly once when it's done reading, which also closes the channel.
+func (w *Writer) Subscribe() (ch <-chan Vertex, cancel func()) {
+	c := make(chan Vertex, subscriberBuffer)
+
+	w.mu.Lock()
+	w.subscribers[c] = struct{}{}
+	w.mu.Unlock()
+
+	return c, func() {
+		w.mu.Lock()
+		delete(w.subscribers, c)
+		w.mu.Unlock()
+		close(c)
+	}
+}
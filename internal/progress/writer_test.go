@@ -0,0 +1,71 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterSubscribe(t *testing.T) {
+	// Arrange
+	w := NewWriter()
+	ch, cancel := w.Subscribe()
+	defer cancel()
+
+	expected := Vertex{Name: "plot-k32.plot", Status: StatusTransferring, Written: 10, Total: 100}
+
+	// Act
+	w.Write(expected)
+
+	// Assert
+	select {
+	case actual := <-ch:
+		require.Equal(t, expected, actual)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for vertex")
+	}
+}
+
+func TestWriterCancel(t *testing.T) {
+	// Arrange
+	w := NewWriter()
+	ch, cancel := w.Subscribe()
+
+	// Act
+	cancel()
+	w.Write(Vertex{Name: "plot-k32.plot", Status: StatusDone})
+
+	// Assert
+	_, ok := <-ch
+	require.False(t, ok)
+}
+
+func TestWriterNoSubscribers(t *testing.T) {
+	// Arrange
+	w := NewWriter()
+
+	// Act & Assert: must not panic or block with nobody listening.
+	w.Write(Vertex{Name: "plot-k32.plot", Status: StatusQueued})
+}
@@ -0,0 +1,141 @@
+//go:build darwin
+
+/*
+Copyright © 2023 Taylor Paddock <tcpaddock@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+type copyMethod int
+
+const (
+	methodUnknown copyMethod = iota
+	methodRename
+	methodClonefile
+	methodUserspace
+)
+
+type mountPair struct {
+	srcDev, dstDev uint64
+}
+
+var (
+	copyMethodCacheMu sync.Mutex
+	copyMethodCache   = map[mountPair]copyMethod{}
+)
+
+// moveFile moves src to dstName, preferring os.Rename on the same mount and
+// clonefile(2) (APFS copy-on-write) otherwise. consumed reports whether src
+// has already been unlinked as a side effect (true for rename).
+func moveFile(src *os.File, dstName string) (written int64, consumed bool, err error) {
+	srcInfo, err := src.Stat()
+	if err != nil {
+		return 0, false, err
+	}
+
+	srcStat, ok := srcInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return copyUserspace(src, dstName)
+	}
+
+	dstDev, ok := statDev(filepath.Dir(dstName))
+	if !ok {
+		return copyUserspace(src, dstName)
+	}
+
+	pair := mountPair{srcDev: uint64(srcStat.Dev), dstDev: dstDev}
+	size := srcInfo.Size()
+
+	switch cachedMethod(pair) {
+	case methodUserspace:
+		return copyUserspace(src, dstName)
+	case methodRename:
+		if err = os.Rename(src.Name(), dstName); err == nil {
+			return size, true, nil
+		}
+	case methodClonefile:
+		if err = unix.Clonefile(src.Name(), dstName, 0); err == nil {
+			return size, false, nil
+		}
+	}
+
+	if err = os.Rename(src.Name(), dstName); err == nil {
+		setCachedMethod(pair, methodRename)
+		return size, true, nil
+	}
+	if !errors.Is(err, unix.EXDEV) {
+		if !isUnsupported(err) {
+			return 0, false, err
+		}
+		setCachedMethod(pair, methodUserspace)
+		return copyUserspace(src, dstName)
+	}
+
+	if err = unix.Clonefile(src.Name(), dstName, 0); err == nil {
+		setCachedMethod(pair, methodClonefile)
+		return size, false, nil
+	}
+	if !isUnsupported(err) {
+		return 0, false, err
+	}
+
+	setCachedMethod(pair, methodUserspace)
+	return copyUserspace(src, dstName)
+}
+
+// isUnsupported reports whether err is one of the documented fallback
+// errnos (the destination mount doesn't support clonefile, or the two
+// mounts aren't the same filesystem), as opposed to a transient or
+// permission error that shouldn't permanently pin this mountPair to the
+// slow userspace copy path.
+func isUnsupported(err error) bool {
+	return errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EXDEV)
+}
+
+func cachedMethod(pair mountPair) copyMethod {
+	copyMethodCacheMu.Lock()
+	defer copyMethodCacheMu.Unlock()
+	return copyMethodCache[pair]
+}
+
+func setCachedMethod(pair mountPair, method copyMethod) {
+	copyMethodCacheMu.Lock()
+	defer copyMethodCacheMu.Unlock()
+	copyMethodCache[pair] = method
+}
+
+func statDev(dir string) (dev uint64, ok bool) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(dir, &st); err != nil {
+		return 0, false
+	}
+
+	return uint64(st.Dev), true
+}
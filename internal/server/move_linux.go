@@ -0,0 +1,203 @@
+//go:build linux
+
+/*
+Copyright © 2023 Taylor Paddock <tcpaddock@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyMethod is the fastest known way to move bytes between a given pair of
+// mounts, discovered once and reused for every subsequent plot between the
+// same two filesystems.
+type copyMethod int
+
+const (
+	methodUnknown copyMethod = iota
+	methodRename
+	methodReflink
+	methodCopyFileRange
+	methodUserspace
+)
+
+// mountPair identifies a (source device, destination device) combination, as
+// reported by stat(2), so capability detection only needs to happen once per
+// pair rather than once per plot.
+type mountPair struct {
+	srcDev, dstDev uint64
+}
+
+var (
+	copyMethodCacheMu sync.Mutex
+	copyMethodCache   = map[mountPair]copyMethod{}
+)
+
+// moveFile moves src to dstName, preferring an in-kernel move (rename,
+// reflink, or copy_file_range) over a userspace copy when the source and
+// destination mounts support it. consumed reports whether src has already
+// been unlinked as a side effect (true for rename), so the caller should
+// skip its own os.Remove.
+func moveFile(src *os.File, dstName string) (written int64, consumed bool, err error) {
+	srcInfo, err := src.Stat()
+	if err != nil {
+		return 0, false, err
+	}
+
+	srcStat, ok := srcInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return copyUserspace(src, dstName)
+	}
+
+	dstDev, ok := statDev(filepath.Dir(dstName))
+	if !ok {
+		return copyUserspace(src, dstName)
+	}
+
+	pair := mountPair{srcDev: srcStat.Dev, dstDev: dstDev}
+	size := srcInfo.Size()
+
+	switch cachedMethod(pair) {
+	case methodUserspace:
+		return copyUserspace(src, dstName)
+	case methodRename:
+		if err = os.Rename(src.Name(), dstName); err == nil {
+			return size, true, nil
+		}
+	case methodReflink:
+		if written, err = tryReflink(src, dstName, size); err == nil {
+			return written, false, nil
+		}
+	case methodCopyFileRange:
+		if written, err = tryCopyFileRange(src, dstName, size); err == nil {
+			return written, false, nil
+		}
+	}
+
+	// No usable cached method (or the cached one unexpectedly failed):
+	// probe every method in order of preference and cache the winner.
+	if err = os.Rename(src.Name(), dstName); err == nil {
+		setCachedMethod(pair, methodRename)
+		return size, true, nil
+	}
+	if !errors.Is(err, unix.EXDEV) {
+		if !isUnsupported(err) {
+			return 0, false, err
+		}
+		setCachedMethod(pair, methodUserspace)
+		return copyUserspace(src, dstName)
+	}
+
+	if written, err = tryReflink(src, dstName, size); err == nil {
+		setCachedMethod(pair, methodReflink)
+		return written, false, nil
+	}
+	if !isUnsupported(err) {
+		return 0, false, err
+	}
+
+	if written, err = tryCopyFileRange(src, dstName, size); err == nil {
+		setCachedMethod(pair, methodCopyFileRange)
+		return written, false, nil
+	}
+	if !isUnsupported(err) {
+		return 0, false, err
+	}
+
+	setCachedMethod(pair, methodUserspace)
+	return copyUserspace(src, dstName)
+}
+
+func cachedMethod(pair mountPair) copyMethod {
+	copyMethodCacheMu.Lock()
+	defer copyMethodCacheMu.Unlock()
+	return copyMethodCache[pair]
+}
+
+func setCachedMethod(pair mountPair, method copyMethod) {
+	copyMethodCacheMu.Lock()
+	defer copyMethodCacheMu.Unlock()
+	copyMethodCache[pair] = method
+}
+
+func statDev(dir string) (dev uint64, ok bool) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(dir, &st); err != nil {
+		return 0, false
+	}
+
+	return st.Dev, true
+}
+
+// tryReflink clones src onto a newly created dstName via the FICLONE ioctl,
+// which copy-on-write filesystems like btrfs and XFS (with reflink=1) can
+// complete without reading or writing a single data block.
+func tryReflink(src *os.File, dstName string, size int64) (written int64, err error) {
+	dst, err := os.Create(dstName)
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close()
+
+	if err = unix.IoctlFileClone(int(dst.Fd()), int(src.Fd())); err != nil {
+		os.Remove(dstName)
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// tryCopyFileRange copies src to a newly created dstName entirely within the
+// kernel via copy_file_range(2), which avoids the userspace round trip of
+// io.Copy even when the filesystem can't reflink.
+func tryCopyFileRange(src *os.File, dstName string, size int64) (written int64, err error) {
+	dst, err := os.Create(dstName)
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close()
+
+	var total int64
+	for total < size {
+		n, err := unix.CopyFileRange(int(src.Fd()), nil, int(dst.Fd()), nil, int(size-total), 0)
+		if err != nil {
+			os.Remove(dstName)
+			return 0, err
+		}
+		if n == 0 {
+			break
+		}
+		total += int64(n)
+	}
+
+	return total, nil
+}
+
+func isUnsupported(err error) bool {
+	return errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EINVAL) || errors.Is(err, unix.EXDEV)
+}
@@ -1,5 +1,7 @@
+//go:build !linux && !darwin
+
 /*
-Copyright © 2023 Taylor Paddock
+Copyright © 2023 Taylor Paddock <tcpaddock@gmail.com>
 
 Permission is hereby granted, free of charge, to any person obtaining a copy
 of this software and associated documentation files (the "Software"), to deal
@@ -19,23 +21,13 @@ LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
 OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 THE SOFTWARE.
 */
-package tcp
-
-import (
-	"github.com/tcpaddock/shiplot/internal/config"
-	"github.com/tcpaddock/shiplot/internal/sower"
-)
-
-type Client struct {
-	cfg   config.Config
-	sower *sower.Sower
-}
-
-func NewClient(cfg config.Config, sower *sower.Sower) (c *Client) {
-	c = new(Client)
+package server
 
-	c.cfg = cfg
-	c.sower = sower
+import "os"
 
-	return c
+// moveFile has no in-kernel fast path on this platform (notably Windows,
+// which has no reflink/copy_file_range equivalent exposed to Go), so it
+// always falls back to a userspace copy.
+func moveFile(src *os.File, dstName string) (written int64, consumed bool, err error) {
+	return copyUserspace(src, dstName)
 }
@@ -39,6 +39,10 @@ func NewPlot(name string) (p *plot) {
 	return p
 }
 
+// Move relocates the plot to name, preferring an in-kernel copy (same-mount
+// rename, reflink, or copy_file_range) over a userspace io.Copy when the
+// platform and destination filesystem support it. moveFile picks the method
+// per platform; see move_linux.go, move_darwin.go, and move_other.go.
 func (p *plot) Move(name string) (file os.FileInfo, written int64, duration time.Duration, err error) {
 	src, err := os.Open(p.Name)
 	if err != nil {
@@ -46,44 +50,54 @@ func (p *plot) Move(name string) (file os.FileInfo, written int64, duration time
 		return nil, 0, 0, err
 	}
 
-	dst, err := os.Create(name)
+	start := time.Now()
+	written, consumed, err := moveFile(src, name)
+	duration = time.Since(start)
 	if err != nil {
 		src.Close()
-		dst.Close()
 		return nil, 0, 0, err
 	}
 
-	start := time.Now()
-	written, err = io.Copy(dst, src)
-	duration = time.Since(start)
+	err = src.Close()
 	if err != nil {
-		src.Close()
-		dst.Close()
 		return nil, 0, 0, err
 	}
 
-	file, err = dst.Stat()
+	file, err = os.Stat(name)
 	if err != nil {
-		src.Close()
-		dst.Close()
 		return nil, 0, 0, err
 	}
 
-	err = src.Close()
+	// moveFile already unlinked the source when it moved it in-kernel
+	if !consumed {
+		err = os.Remove(src.Name())
+		if err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	return file, written, duration, nil
+}
+
+// copyUserspace is the fallback used by every platform when no faster method
+// applies: a plain io.Copy into a newly created destination file.
+func copyUserspace(src *os.File, dstName string) (written int64, consumed bool, err error) {
+	dst, err := os.Create(dstName)
 	if err != nil {
-		return nil, 0, 0, err
+		dst.Close()
+		return 0, false, err
 	}
 
-	err = dst.Close()
+	written, err = io.Copy(dst, src)
 	if err != nil {
-		return nil, 0, 0, err
+		dst.Close()
+		return 0, false, err
 	}
 
-	// copy succeeded, delete source
-	err = os.Remove(src.Name())
+	err = dst.Close()
 	if err != nil {
-		return nil, 0, 0, err
+		return 0, false, err
 	}
 
-	return file, written, duration, nil
+	return written, false, nil
 }
@@ -25,7 +25,9 @@ import (
 	"context"
 
 	"github.com/tcpaddock/shiplot/internal/config"
+	"github.com/tcpaddock/shiplot/internal/progress"
 	"github.com/tcpaddock/shiplot/internal/sower"
+	"github.com/tcpaddock/shiplot/internal/sower/ctl"
 	"golang.org/x/exp/slog"
 )
 
@@ -46,7 +48,36 @@ func NewServer(cfg config.Config) (s *Server, err error) {
 	return s, nil
 }
 
+// Progress returns the Writer that Vertex updates for every transfer are
+// published to, for callers that want to attach their own renderer (e.g.
+// runCmd's --progress flag) instead of or in addition to the status socket.
+func (s *Server) Progress() *progress.Writer {
+	return s.sower.Progress()
+}
+
 func (s *Server) Start(ctx context.Context) (err error) {
+	if s.cfg.Progress.SocketPath != "" {
+		slog.Default().Info("Starting status socket", slog.String("path", s.cfg.Progress.SocketPath))
+		ss := progress.NewSocketServer(s.sower.Progress(), s.cfg.Progress.SocketPath)
+
+		go func() {
+			if err := ss.Run(ctx); err != nil {
+				slog.Default().Error("Status socket stopped", err)
+			}
+		}()
+	}
+
+	if s.cfg.Control.SocketPath != "" {
+		slog.Default().Info("Starting control socket", slog.String("path", s.cfg.Control.SocketPath))
+		cs := ctl.NewServer(s.sower, s.cfg.Control.SocketPath)
+
+		go func() {
+			if err := cs.Run(ctx); err != nil {
+				slog.Default().Error("Control socket stopped", err)
+			}
+		}()
+	}
+
 	if s.cfg.Server.Enabled {
 		slog.Default().Info("Starting server")
 		ts := sower.NewTcpServer(s.cfg, s.sower)
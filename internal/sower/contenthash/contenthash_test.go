@@ -0,0 +1,89 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package contenthash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSumMatchesKnownDigest(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "plot")
+	require.NoError(t, os.WriteFile(path, []byte("hello world"), 0o644))
+
+	// Act
+	d, err := Sum(path)
+
+	// Assert: BLAKE2b-256("hello world")
+	require.NoError(t, err)
+	require.Equal(t, "256c83b297114d201b30179f3f0ef0cace9783622da5974326b436178aeef610", hexString(d))
+}
+
+func TestVerifyDetectsMismatch(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "plot")
+	require.NoError(t, os.WriteFile(path, []byte("hello world"), 0o644))
+	want, err := Sum(path)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("corrupted"), 0o644))
+
+	// Act
+	ok, err := Verify(path, want)
+
+	// Assert
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestCacheGetSetDelete(t *testing.T) {
+	// Arrange
+	c := NewCache()
+	var d Digest
+	d[0] = 0xAB
+
+	// Act & Assert: absent before Set
+	_, ok := c.Get("/plots/a")
+	require.False(t, ok)
+
+	c.Set("/plots/a", d)
+	got, ok := c.Get("/plots/a")
+	require.True(t, ok)
+	require.Equal(t, d, got)
+
+	c.Delete("/plots/a")
+	_, ok = c.Get("/plots/a")
+	require.False(t, ok)
+}
+
+func hexString(d Digest) string {
+	const hextable = "0123456789abcdef"
+	out := make([]byte, 0, len(d)*2)
+	for _, b := range d {
+		out = append(out, hextable[b>>4], hextable[b&0x0f])
+	}
+	return string(out)
+}
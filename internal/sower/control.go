@@ -0,0 +1,96 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package sower
+
+import "fmt"
+
+// waitIfPaused blocks the calling worker goroutine while the Sower is
+// paused, so a job already submitted to the pool doesn't start doing real
+// work (and claiming a destination reservation) until Resume is called.
+// Jobs queued before or during a pause still show up in Stats once their
+// worker picks them up and blocks here.
+func (s *Sower) waitIfPaused() {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	for s.paused {
+		s.resumeCond.Wait()
+	}
+}
+
+// Pause stops new transfers from starting. Transfers already past
+// waitIfPaused keep running to completion; only ones whose worker hasn't
+// reached that point yet are held.
+func (s *Sower) Pause() {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	s.paused = true
+}
+
+// Resume releases every transfer blocked in waitIfPaused.
+func (s *Sower) Resume() {
+	s.pauseMu.Lock()
+	s.paused = false
+	s.pauseMu.Unlock()
+
+	s.resumeCond.Broadcast()
+}
+
+// Tune resizes the worker pool to n and records n as the new cfg.MaxThreads,
+// so a later call to Stats or a restart reports the operator's change
+// instead of the value run started with.
+func (s *Sower) Tune(n int) {
+	s.pool.Tune(n)
+	s.cfg.MaxThreads = uint(n)
+}
+
+// DropDestination permanently removes the destination path configured at
+// name (as it appears in cfg.DestinationPaths) from the scheduler, so it no
+// longer receives new reservations. It returns an error if no destination
+// is currently registered under that name.
+func (s *Sower) DropDestination(name string) error {
+	p := s.scheduler.FindByName(name)
+	if p == nil {
+		return fmt.Errorf("sower: no destination path %q", name)
+	}
+
+	s.scheduler.Remove(p)
+	return nil
+}
+
+// CancelJob cancels the in-flight transfer registered under name (the
+// plot's base file name, the same identifier Stats reports it under),
+// stopping it the same way its context expiring on its own would. It
+// returns an error if no transfer is currently registered under that name.
+func (s *Sower) CancelJob(name string) error {
+	s.transfersMu.Lock()
+	t, ok := s.transfers[name]
+	s.transfersMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("sower: no transfer named %q", name)
+	}
+
+	t.cancel()
+	return nil
+}
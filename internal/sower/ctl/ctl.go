@@ -0,0 +1,180 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package ctl exposes a running Sower's *sower.Sower over a Unix domain
+// socket speaking a small newline-delimited JSON protocol, so an operator
+// can inspect and steer a long-running "shiplot run" (via the "shiplot ctl"
+// subcommand) without killing it: "status" for per-transfer bytes/rate/ETA,
+// "pause"/"resume" to gate new transfers, "tune" to resize the worker pool,
+// "drop" to take a destination out of rotation, and "cancel" to stop one
+// in-flight transfer.
+package ctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/tcpaddock/shiplot/internal/sower"
+	"golang.org/x/exp/slog"
+)
+
+// Controller is the subset of *sower.Sower's exported surface a Server
+// drives. It exists so Server doesn't have to take a concrete *sower.Sower,
+// keeping this package easy to exercise with a fake in tests.
+type Controller interface {
+	Stats() []sower.TransferStats
+	Pause()
+	Resume()
+	Tune(n int)
+	DropDestination(name string) error
+	CancelJob(name string) error
+}
+
+// Request is one line of the ctl protocol sent by "shiplot ctl" to Server.
+type Request struct {
+	// Cmd is one of "status", "pause", "resume", "tune", "drop" or "cancel".
+	Cmd string `json:"cmd"`
+
+	// N is the worker pool size for "tune".
+	N int `json:"n,omitempty"`
+
+	// Path is the destination path to remove for "drop".
+	Path string `json:"path,omitempty"`
+
+	// Job is the transfer name to stop for "cancel".
+	Job string `json:"job,omitempty"`
+}
+
+// Response is Server's reply to one Request.
+type Response struct {
+	OK    bool                  `json:"ok"`
+	Error string                `json:"error,omitempty"`
+	Stats []sower.TransferStats `json:"stats,omitempty"`
+}
+
+// Server accepts connections on a Unix socket and answers each Request it
+// reads with a Response, driving ctl against the Controller it was built
+// with.
+type Server struct {
+	ctl  Controller
+	path string
+}
+
+// NewServer returns a Server that drives ctl and, once Run is called,
+// listens at path.
+func NewServer(ctl Controller, path string) *Server {
+	return &Server{ctl: ctl, path: path}
+}
+
+// Run listens on s.path until ctx is cancelled, serving every connection
+// concurrently. The socket's containing directory is created 0700 and the
+// socket itself is chmod'd 0600, since anyone who can reach it can pause
+// transfers or drop a destination.
+func (s *Server) Run(ctx context.Context) (err error) {
+	if err = os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	os.Remove(s.path)
+
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	if err = os.Chmod(s.path, 0600); err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			slog.Default().Error("Failed to accept control connection", err)
+			continue
+		}
+
+		go s.serve(conn)
+	}
+}
+
+// serve answers every Request conn sends until it's closed or sends
+// something that doesn't decode as one.
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+
+	for {
+		var req Request
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+
+		if err := enc.Encode(s.handle(req)); err != nil {
+			return
+		}
+	}
+}
+
+// handle runs one Request against s.ctl and builds its Response.
+func (s *Server) handle(req Request) Response {
+	switch req.Cmd {
+	case "status":
+		return Response{OK: true, Stats: s.ctl.Stats()}
+	case "pause":
+		s.ctl.Pause()
+		return Response{OK: true}
+	case "resume":
+		s.ctl.Resume()
+		return Response{OK: true}
+	case "tune":
+		s.ctl.Tune(req.N)
+		return Response{OK: true}
+	case "drop":
+		if err := s.ctl.DropDestination(req.Path); err != nil {
+			return Response{Error: err.Error()}
+		}
+		return Response{OK: true}
+	case "cancel":
+		if err := s.ctl.CancelJob(req.Job); err != nil {
+			return Response{Error: err.Error()}
+		}
+		return Response{OK: true}
+	default:
+		return Response{Error: fmt.Sprintf("unknown command %q", req.Cmd)}
+	}
+}
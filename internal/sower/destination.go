@@ -0,0 +1,245 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package sower
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	s3afero "github.com/fclairamb/afero-s3"
+	"github.com/pkg/sftp"
+	"github.com/spf13/afero/sftpfs"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// b2Endpoint is B2's S3-compatible API endpoint for region, e.g.
+// "s3.us-west-002.backblazeb2.com". cfg.DestinationPaths entries for b2://
+// destinations carry the region as the URL host, the same way s3:// carries
+// the bucket.
+func b2Endpoint(region string) string {
+	return fmt.Sprintf("s3.%s.backblazeb2.com", region)
+}
+
+// newDestination parses one entry of cfg.DestinationPaths into a path
+// backed by the appropriate afero.Fs. A bare filesystem path or a
+// "local://" URL is a local directory; "sftp://", "s3://" and "b2://" dial
+// out to a remote destination instead. Every scheme reports free space
+// differently through FreeSpacer.
+func newDestination(rawPath string) (p *path, err error) {
+	u, err := url.Parse(rawPath)
+	if err != nil || u.Scheme == "" {
+		return newPath(rawPath), nil
+	}
+
+	switch u.Scheme {
+	case "local":
+		return newPath(u.Path), nil
+	case "sftp":
+		return newSFTPDestination(u)
+	case "s3":
+		return newS3Destination(u, "")
+	case "b2":
+		return newS3Destination(u, b2Endpoint(u.Host))
+	default:
+		return nil, fmt.Errorf("sower: unsupported destination scheme %q", u.Scheme)
+	}
+}
+
+// sftpFreeSpacer reports free space on a remote SFTP destination via the
+// statvfs@openssh.com extension.
+type sftpFreeSpacer struct {
+	client *sftp.Client
+	path   string
+}
+
+func (f *sftpFreeSpacer) stat() (*sftp.StatVFS, error) {
+	return f.client.StatVFS(f.path)
+}
+
+func (f *sftpFreeSpacer) Free() uint64 {
+	st, err := f.stat()
+	if err != nil {
+		return 0
+	}
+
+	return st.Frsize * st.Bavail
+}
+
+func (f *sftpFreeSpacer) Size() uint64 {
+	st, err := f.stat()
+	if err != nil {
+		return 0
+	}
+
+	return st.Frsize * st.Blocks
+}
+
+// newSFTPDestination dials u.Host (authenticating as u.User over the local
+// SSH agent, the conventional way to avoid storing a key or password in
+// cfg.DestinationPaths) and wraps the resulting *sftp.Client in afero's
+// sftpfs, so the rest of the sower package never has to know a destination
+// isn't local.
+func newSFTPDestination(u *url.URL) (p *path, err error) {
+	agentClient, err := sftpAgentDial()
+	if err != nil {
+		return nil, fmt.Errorf("sower: failed to connect to ssh-agent for %s: %w", u.Host, err)
+	}
+
+	username := "shiplot"
+	if u.User != nil {
+		username = u.User.Username()
+	}
+
+	hostKeyCallback, err := sftpHostKeyCallback(u)
+	if err != nil {
+		return nil, fmt.Errorf("sower: failed to load known_hosts for %s: %w", u.Host, err)
+	}
+
+	sshClient, err := ssh.Dial("tcp", u.Host, &ssh.ClientConfig{
+		User:            username,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeysCallback(agentClient.Signers)},
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sower: failed to dial sftp destination %s: %w", u.Host, err)
+	}
+
+	sftpClient, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, fmt.Errorf("sower: failed to start sftp session with %s: %w", u.Host, err)
+	}
+
+	dir := u.Path
+	if dir == "" {
+		dir = "/"
+	}
+
+	return &path{
+		name:  dir,
+		fs:    sftpfs.New(sftpClient),
+		usage: &sftpFreeSpacer{client: sftpClient, path: dir},
+		index: -1,
+	}, nil
+}
+
+// quotaFreeSpacer reports a fixed, configured capacity for destinations
+// whose protocol doesn't expose live free space, like S3 and B2. Capacity
+// is whatever quotaBytes the cfg.DestinationPaths URL advertises via a
+// "?quotaBytes=" query parameter; the Scheduler still tracks in-flight
+// reservations against it the same way it does for a local path.
+type quotaFreeSpacer struct {
+	quotaBytes uint64
+}
+
+func (f *quotaFreeSpacer) Free() uint64 { return f.quotaBytes }
+func (f *quotaFreeSpacer) Size() uint64 { return f.quotaBytes }
+
+// newS3Destination configures an S3-compatible destination: u.Host is the
+// bucket, u.Path is the key prefix plots are written under, and endpoint
+// overrides the default AWS endpoint resolution for S3-compatible services
+// like B2 that speak the same API under their own domain.
+//
+// Credentials are never read from rawPath/u: embedding a secret key in a
+// --destinationPaths URL would put it in ps(1) output and shell history.
+// Instead this uses the AWS SDK's default provider chain (the
+// AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY env vars, the ~/.aws/credentials
+// file, or an EC2/ECS instance role), optionally scoped to a named profile
+// via a "?profile=" query parameter the same way quotaBytes is passed.
+func newS3Destination(u *url.URL, endpoint string) (p *path, err error) {
+	if u.User != nil {
+		return nil, fmt.Errorf("sower: destination %s must not embed credentials in the URL; set AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY or a profile via \"?profile=\" instead", u.Host)
+	}
+
+	awsCfg := aws.NewConfig()
+	if endpoint != "" {
+		awsCfg = awsCfg.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsCfg,
+		Profile:           u.Query().Get("profile"),
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sower: failed to create session for %s: %w", u.Host, err)
+	}
+
+	var quotaBytes uint64
+	if q := u.Query().Get("quotaBytes"); q != "" {
+		quotaBytes, err = strconv.ParseUint(q, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("sower: invalid quotaBytes for %s: %w", u.Host, err)
+		}
+	}
+
+	return &path{
+		name:  u.Path,
+		fs:    s3afero.NewFs(u.Host, sess),
+		usage: &quotaFreeSpacer{quotaBytes: quotaBytes},
+		index: -1,
+	}, nil
+}
+
+// sftpHostKeyCallback returns a HostKeyCallback that verifies the remote
+// host key against a known_hosts(5) file, the same trust store ssh(1)
+// itself uses: u's "?knownHosts=" query parameter names the file if given,
+// otherwise $HOME/.ssh/known_hosts is used. shiplot has no separate
+// key-pinning mechanism, so this file is what stands between an sftp://
+// destination and a MITM'd "sftp server" impersonating it.
+func sftpHostKeyCallback(u *url.URL) (ssh.HostKeyCallback, error) {
+	path := u.Query().Get("knownHosts")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	return knownhosts.New(path)
+}
+
+// sftpAgentDial connects to the local ssh-agent over $SSH_AUTH_SOCK, the
+// same mechanism ssh(1) itself uses, so newSFTPDestination can authenticate
+// without cfg ever holding a private key.
+func sftpAgentDial() (agent.ExtendedAgent, error) {
+	sockPath := os.Getenv("SSH_AUTH_SOCK")
+	if sockPath == "" {
+		return nil, fmt.Errorf("SSH_AUTH_SOCK is not set")
+	}
+
+	conn, err := net.Dial("unix", sockPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return agent.NewClient(conn), nil
+}
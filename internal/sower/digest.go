@@ -0,0 +1,103 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package sower
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/exp/slog"
+)
+
+// blake2bDigestSize is the size in bytes of a BLAKE2b-256 digest.
+const blake2bDigestSize = 32
+
+// sidecarPath returns the path of plotPath's BLAKE2b-256 sidecar file.
+func sidecarPath(plotPath string) string {
+	return plotPath + ".b2"
+}
+
+// writeSidecar records sum as plotPath's sidecar digest, so `shiplot verify`
+// can later audit the destination independently of the transfer that
+// produced it.
+func writeSidecar(plotPath string, sum [blake2bDigestSize]byte) error {
+	return os.WriteFile(sidecarPath(plotPath), []byte(hex.EncodeToString(sum[:])+"\n"), 0644)
+}
+
+// ReadSidecar reads and decodes path's ".b2" sidecar digest.
+func ReadSidecar(path string) (sum [blake2bDigestSize]byte, err error) {
+	b, err := os.ReadFile(sidecarPath(path))
+	if err != nil {
+		return sum, err
+	}
+
+	decoded, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return sum, err
+	}
+	if len(decoded) != blake2bDigestSize {
+		return sum, fmt.Errorf("%s does not contain a %d-byte digest", sidecarPath(path), blake2bDigestSize)
+	}
+	copy(sum[:], decoded)
+
+	return sum, nil
+}
+
+// HashFile computes the BLAKE2b-256 digest of the file at path.
+func HashFile(path string) (sum [blake2bDigestSize]byte, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+
+	h, err := blake2b.New256(nil)
+	if err != nil {
+		return sum, err
+	}
+	if _, err = io.Copy(h, f); err != nil {
+		return sum, err
+	}
+
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// writePlotSidecar hashes the landed plot at path and records the digest in
+// its .b2 sidecar so `shiplot verify` can later audit it. Failures are
+// logged rather than returned, since a missing sidecar shouldn't fail a
+// transfer that otherwise landed successfully.
+func writePlotSidecar(path string) {
+	sum, err := HashFile(path)
+	if err != nil {
+		slog.Default().Error(fmt.Sprintf("failed to hash %s for digest sidecar", path), err)
+		return
+	}
+
+	if err = writeSidecar(path, sum); err != nil {
+		slog.Default().Error(fmt.Sprintf("failed to write digest sidecar for %s", path), err)
+	}
+}
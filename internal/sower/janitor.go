@@ -0,0 +1,100 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package sower
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// janitorInterval is how often runJanitor sweeps destination paths for
+// stale partial downloads.
+const janitorInterval = 10 * time.Minute
+
+// defaultResumeTTL is used when cfg.Server.ResumeTTL isn't set.
+const defaultResumeTTL = 24 * time.Hour
+
+// runJanitor periodically deletes ".tmp" partial downloads (and their
+// ".journal" checkpoints, see journal.go) that haven't been written to in
+// longer than ttl, reclaiming space a client abandoned instead of
+// resuming. It loops, sweeping once immediately and then every
+// janitorInterval, until ctx is cancelled. ttl <= 0 falls back to
+// defaultResumeTTL.
+func (s *Sower) runJanitor(ctx context.Context, ttl time.Duration) {
+	defer s.wg.Done()
+
+	if ttl <= 0 {
+		ttl = defaultResumeTTL
+	}
+
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+
+	s.sweepStalePartials(ttl)
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sweepStalePartials(ttl)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Sower) sweepStalePartials(ttl time.Duration) {
+	for _, destPath := range s.destPaths {
+		// Remote destinations (sftp://, s3://, b2://) aren't swept yet; only
+		// the local os.ReadDir-based walk below is implemented.
+		if strings.Contains(destPath, "://") {
+			continue
+		}
+
+		entries, err := os.ReadDir(destPath)
+		if err != nil {
+			slog.Default().Error(fmt.Sprintf("janitor failed to list %s", destPath), err)
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tmp") {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil || time.Since(info.ModTime()) < ttl {
+				continue
+			}
+
+			fullName := filepath.Join(destPath, entry.Name())
+			slog.Default().Info("Removing stale partial download", slog.String("name", fullName), slog.Duration("age", time.Since(info.ModTime())))
+			os.Remove(fullName)
+			removeJournal(fullName)
+		}
+	}
+}
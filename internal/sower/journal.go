@@ -0,0 +1,78 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package sower
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+)
+
+// transferJournal records the progress of an in-flight plot download so a
+// dropped connection can resume mid-file instead of restarting from zero.
+// It lives as a sidecar of the ".tmp" destination file for the duration of
+// the transfer and is removed once the plot is verified and renamed.
+type transferJournal struct {
+	Digest        string `json:"digest"`
+	BytesReceived uint64 `json:"bytesReceived"`
+}
+
+func journalPath(tmpName string) string {
+	return tmpName + ".journal"
+}
+
+// readJournal loads the journal for tmpName, returning ok=false if it
+// doesn't exist or doesn't match the offered digest.
+func readJournal(tmpName string, digest [digestSize]byte) (j transferJournal, ok bool) {
+	b, err := os.ReadFile(journalPath(tmpName))
+	if err != nil {
+		return transferJournal{}, false
+	}
+
+	if err = json.Unmarshal(b, &j); err != nil {
+		return transferJournal{}, false
+	}
+
+	if j.Digest != hex.EncodeToString(digest[:]) {
+		return transferJournal{}, false
+	}
+
+	return j, true
+}
+
+func writeJournal(tmpName string, digest [digestSize]byte, bytesReceived uint64) error {
+	j := transferJournal{
+		Digest:        hex.EncodeToString(digest[:]),
+		BytesReceived: bytesReceived,
+	}
+
+	b, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(journalPath(tmpName), b, 0644)
+}
+
+func removeJournal(tmpName string) {
+	_ = os.Remove(journalPath(tmpName))
+}
@@ -22,85 +22,59 @@ THE SOFTWARE.
 package sower
 
 import (
-	"sort"
-	"sync"
-
 	"github.com/ricochet2200/go-disk-usage/du"
-	"golang.org/x/exp/slices"
+	"github.com/spf13/afero"
 )
 
-type path struct {
-	name      string
-	usage     *du.DiskUsage
-	available bool
+// FreeSpacer reports how much space remains on a destination, and how big
+// it is in total. Each backend implements it differently: a local path
+// statfs's the filesystem it lives on, sftp asks the remote host over the
+// statvfs@openssh.com extension, and s3/b2 report the fixed quota
+// configured for that destination since neither protocol exposes live free
+// space the way a local or SFTP filesystem does.
+type FreeSpacer interface {
+	Free() uint64
+	Size() uint64
 }
 
-type pathList []*path
-
-var pathListMutex sync.Mutex
-
-func (pl *pathList) Len() int { return len(*pl) }
-
-func (pl *pathList) Swap(i, j int) { (*pl)[i], (*pl)[j] = (*pl)[j], (*pl)[i] }
-
-func (pl *pathList) Less(i, j int) bool { return (*pl)[i].usage.Free() > (*pl)[j].usage.Free() }
-
-func (pl *pathList) Populate(paths []string) {
-	pathListMutex.Lock()
-
-	for _, p := range paths {
-		usage := du.NewDiskUsage(p)
-		*pl = append(*pl, &path{name: p, usage: usage, available: true})
-	}
-
-	pathListMutex.Unlock()
+// path is one configured destination tracked by a Scheduler. It is only
+// ever mutated through the Scheduler that owns it, which holds the lock
+// protecting reserved and index. fs performs all actual file operations
+// against the destination, whether that's the local disk, an SFTP server,
+// or an S3/B2-compatible bucket; name is the directory (or key prefix)
+// within fs that plots are written to.
+type path struct {
+	name     string
+	fs       afero.Fs
+	usage    FreeSpacer
+	reserved uint64
+	index    int // position in the Scheduler's heap; -1 once removed
 }
 
-func (pl *pathList) FirstAvailable() (path *path) {
-	pathListMutex.Lock()
-
-	sort.Sort(pl)
-
-	for _, p := range *pl {
-		if p.available {
-			path = p
-			p.available = false
-			break
-		}
-	}
-
-	pathListMutex.Unlock()
-
-	return
+func newPath(name string) *path {
+	return &path{name: name, fs: afero.NewOsFs(), usage: &localFreeSpacer{path: name}, index: -1}
 }
 
-func (pl *pathList) Update(path *path, available bool) {
-	pathListMutex.Lock()
-
-	index := slices.Index(*pl, path)
-
-	p := (*pl)[index]
-	p.available = available
-	p.usage = du.NewDiskUsage(p.name)
-	sort.Sort(pl)
-
-	pathListMutex.Unlock()
+// localFreeSpacer reports live free space for a local destination
+// directory by statfs'ing it fresh on every call, the same way sftp's
+// statvfs@openssh.com extension and (via quotaFreeSpacer) S3/B2's
+// configured quota are always live rather than a snapshot taken once at
+// construction time.
+type localFreeSpacer struct {
+	path string
 }
 
-func (pl *pathList) Remove(path *path) {
-	pathListMutex.Lock()
-
-	var newList pathList
-
-	copy(newList, *pl)
+func (f *localFreeSpacer) Free() uint64 { return du.NewDiskUsage(f.path).Free() }
+func (f *localFreeSpacer) Size() uint64 { return du.NewDiskUsage(f.path).Size() }
 
-	index := slices.Index(newList, path)
-
-	if index >= 0 {
-		newList = append(newList[:index], newList[index+1:]...)
+// free returns the path's free space minus any space already claimed by
+// in-flight reservations, i.e. the space that's actually safe to hand out
+// next.
+func (p *path) free() uint64 {
+	free := p.usage.Free()
+	if p.reserved >= free {
+		return 0
 	}
 
-	pl = &newList
-
-	pathListMutex.Unlock()
+	return free - p.reserved
 }
@@ -24,64 +24,51 @@ package sower
 import (
 	"testing"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
-func TestLen(t *testing.T) {
+// MockDiskUsage is a testify mock of the FreeSpacer interface, letting
+// tests control free/total space without touching the filesystem.
+type MockDiskUsage struct {
+	mock.Mock
+}
+
+func (m *MockDiskUsage) Free() uint64 {
+	args := m.Called()
+	return args.Get(0).(uint64)
+}
+
+func (m *MockDiskUsage) Size() uint64 {
+	args := m.Called()
+	return args.Get(0).(uint64)
+}
+
+func TestPathFree(t *testing.T) {
 	var tests = []struct {
-		inputPaths    []string
-		expectedCount int
+		name     string
+		free     uint64
+		reserved uint64
+		expected uint64
 	}{
-		{inputPaths: []string{"/test1"}, expectedCount: 1},
-		{inputPaths: []string{"/test1", "/test2"}, expectedCount: 2},
-		{inputPaths: []string{"/test1", "/test2", "/test3"}, expectedCount: 3},
+		{name: "nothing reserved", free: 100, reserved: 0, expected: 100},
+		{name: "partially reserved", free: 100, reserved: 40, expected: 60},
+		{name: "fully reserved", free: 100, reserved: 100, expected: 0},
+		{name: "over-reserved", free: 100, reserved: 150, expected: 0},
 	}
 
 	for _, test := range tests {
-		// Arrange
-		pl := new(pathList)
-		for _, p := range test.inputPaths {
-			*pl = append(*pl, &path{name: p, usage: nil, available: true})
-		}
-		expected := test.expectedCount
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			du := &MockDiskUsage{}
+			du.On("Free").Return(test.free)
+			p := &path{name: "/test", usage: du, reserved: test.reserved}
 
-		// Act
-		actual := pl.Len()
+			// Act
+			actual := p.free()
 
-		// Assert
-		require.Equal(t, expected, actual)
+			// Assert
+			require.Equal(t, test.expected, actual)
+		})
 	}
 }
-
-func TestSwap(t *testing.T) {
-	// Arrange
-	pl := new(pathList)
-	*pl = append(*pl, &path{name: "/test1", usage: nil, available: true})
-	*pl = append(*pl, &path{name: "/test2", usage: nil, available: true})
-	expected := pathList{(*pl)[1], (*pl)[0]}
-
-	// Act
-	pl.Swap(0, 1)
-	actual := *pl
-
-	// Assert
-	require.Equal(t, expected, actual)
-}
-
-func TestLess(t *testing.T) {
-	// Arrange
-	du1 := MockDiskUsage{}
-	du1.On("Free").Return(uint64(2))
-	du2 := MockDiskUsage{}
-	du2.On("Free").Return(uint64(1))
-	pl := new(pathList)
-	*pl = append(*pl, &path{name: "/test1", usage: &du1, available: true})
-	*pl = append(*pl, &path{name: "/test2", usage: &du2, available: true})
-	expected := false
-
-	// Act
-	actual := pl.Less(0, 1)
-
-	// Assert
-	require.Equal(t, expected, actual)
-}
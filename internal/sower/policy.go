@@ -0,0 +1,119 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package sower
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// LeastUsedPolicy selects the candidate with the most effective free space,
+// spreading writes evenly across destinations. It's the default policy and
+// matches the old pathList.FirstAvailable's behavior.
+type LeastUsedPolicy struct{}
+
+func (LeastUsedPolicy) Select(candidates []*path) (selected *path) {
+	selected = candidates[0]
+
+	for _, p := range candidates[1:] {
+		if p.free() > selected.free() {
+			selected = p
+		}
+	}
+
+	return selected
+}
+
+// RoundRobinPolicy cycles through candidates in turn regardless of free
+// space, so load is spread evenly over time instead of always piling onto
+// whichever disk happens to be emptiest.
+type RoundRobinPolicy struct {
+	mu   sync.Mutex
+	next int
+}
+
+func (p *RoundRobinPolicy) Select(candidates []*path) *path {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	selected := candidates[p.next%len(candidates)]
+	p.next++
+
+	return selected
+}
+
+// WeightedByFreeSpacePolicy picks a candidate at random, weighted by its
+// effective free space, so a disk with twice the room is twice as likely
+// to be picked rather than always winning outright like LeastUsedPolicy.
+type WeightedByFreeSpacePolicy struct{}
+
+func (WeightedByFreeSpacePolicy) Select(candidates []*path) *path {
+	var total uint64
+	for _, p := range candidates {
+		total += p.free()
+	}
+	if total == 0 {
+		return candidates[0]
+	}
+
+	target := uint64(rand.Int63n(int64(total)))
+
+	var cumulative uint64
+	for _, p := range candidates {
+		cumulative += p.free()
+		if target < cumulative {
+			return p
+		}
+	}
+
+	return candidates[len(candidates)-1]
+}
+
+// MRUPolicy prefers the path that most recently received a reservation, so
+// a burst of small plots lands on the disk already being written to
+// instead of thrashing across every configured destination. It falls back
+// to LeastUsedPolicy the first time, or once its preferred path no longer
+// has room.
+type MRUPolicy struct {
+	mu      sync.Mutex
+	current string
+}
+
+func (p *MRUPolicy) Select(candidates []*path) *path {
+	p.mu.Lock()
+	current := p.current
+	p.mu.Unlock()
+
+	for _, c := range candidates {
+		if c.name == current {
+			return c
+		}
+	}
+
+	selected := LeastUsedPolicy{}.Select(candidates)
+
+	p.mu.Lock()
+	p.current = selected.name
+	p.mu.Unlock()
+
+	return selected
+}
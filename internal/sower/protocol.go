@@ -0,0 +1,324 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package sower
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/tcpaddock/shiplot/internal/util"
+)
+
+// protocolVersion is the current framed wire protocol version. Peers
+// negotiate down to the lowest version they both understand.
+const protocolVersion uint8 = 1
+
+// digestSize is the size in bytes of a blake3-256 digest.
+const digestSize = 32
+
+// dataChunkSize is the amount of plot data carried by a single DATA frame.
+const dataChunkSize = 4 << 20 // 4 MiB
+
+var magic = [4]byte{'S', 'H', 'P', 'L'}
+
+// feature flags advertised in HELLO/HELLO_ACK payloads.
+const (
+	featureResume = 1 << iota
+	featureCompression
+)
+
+// msgType identifies the payload that follows a header.
+type msgType uint16
+
+const (
+	msgHello msgType = iota + 1
+	msgHelloAck
+	msgPlotOffer
+	msgPlotOfferAck
+	msgData
+	msgPlotDone
+	msgError
+)
+
+// errorCode values returned in an ERROR payload.
+type errorCode uint16
+
+const (
+	errUnknown errorCode = iota
+	errRejected
+	errDigestMismatch
+	errBadRequest
+)
+
+// header is the 16-byte fixed frame header that precedes every message:
+// magic[4] + version + flags + msgType + payloadLen.
+type header struct {
+	version    uint8
+	flags      uint8
+	msgType    msgType
+	payloadLen uint64
+}
+
+func writeHeader(ctx context.Context, w io.Writer, h header) (err error) {
+	cw := util.NewContextWriter(ctx, w)
+
+	buf := make([]byte, 16)
+	copy(buf[0:4], magic[:])
+	buf[4] = h.version
+	buf[5] = h.flags
+	binary.LittleEndian.PutUint16(buf[6:8], uint16(h.msgType))
+	binary.LittleEndian.PutUint64(buf[8:16], h.payloadLen)
+
+	_, err = cw.Write(buf)
+	return err
+}
+
+func readHeader(ctx context.Context, r io.Reader) (h header, err error) {
+	cr := util.NewContextReader(ctx, r)
+
+	buf := make([]byte, 16)
+	if _, err = io.ReadFull(cr, buf); err != nil {
+		return header{}, err
+	}
+
+	if string(buf[0:4]) != string(magic[:]) {
+		return header{}, fmt.Errorf("invalid protocol magic %q", buf[0:4])
+	}
+
+	h.version = buf[4]
+	h.flags = buf[5]
+	h.msgType = msgType(binary.LittleEndian.Uint16(buf[6:8]))
+	h.payloadLen = binary.LittleEndian.Uint64(buf[8:16])
+
+	return h, nil
+}
+
+func writeFrame(ctx context.Context, w io.Writer, t msgType, payload []byte) (err error) {
+	if err = writeHeader(ctx, w, header{version: protocolVersion, msgType: t, payloadLen: uint64(len(payload))}); err != nil {
+		return err
+	}
+
+	cw := util.NewContextWriter(ctx, w)
+	_, err = cw.Write(payload)
+	return err
+}
+
+func readFrame(ctx context.Context, r io.Reader) (h header, payload []byte, err error) {
+	h, err = readHeader(ctx, r)
+	if err != nil {
+		return header{}, nil, err
+	}
+
+	cr := util.NewContextReader(ctx, r)
+	payload = make([]byte, h.payloadLen)
+	if _, err = io.ReadFull(cr, payload); err != nil {
+		return header{}, nil, err
+	}
+
+	return h, payload, nil
+}
+
+// helloPayload is sent by the client to advertise its protocol version and
+// the optional features (resume, compression) it supports.
+type helloPayload struct {
+	version  uint8
+	features uint8
+}
+
+func encodeHello(p helloPayload) []byte {
+	return []byte{p.version, p.features}
+}
+
+func decodeHello(b []byte) (p helloPayload, err error) {
+	if len(b) < 2 {
+		return helloPayload{}, fmt.Errorf("short HELLO payload")
+	}
+	return helloPayload{version: b[0], features: b[1]}, nil
+}
+
+// helloAckPayload is sent by the server with the feature set it agreed to
+// use for the rest of the session (the intersection of both peers' support).
+type helloAckPayload struct {
+	version  uint8
+	features uint8
+}
+
+func encodeHelloAck(p helloAckPayload) []byte {
+	return []byte{p.version, p.features}
+}
+
+func decodeHelloAck(b []byte) (p helloAckPayload, err error) {
+	if len(b) < 2 {
+		return helloAckPayload{}, fmt.Errorf("short HELLO_ACK payload")
+	}
+	return helloAckPayload{version: b[0], features: b[1]}, nil
+}
+
+// plotOfferPayload describes the plot the client wants to send: its name,
+// total size, and the blake3 digest of the full file contents.
+type plotOfferPayload struct {
+	name   string
+	size   uint64
+	digest [digestSize]byte
+}
+
+func encodePlotOffer(p plotOfferPayload) []byte {
+	buf := make([]byte, 1+len(p.name)+8+digestSize)
+	buf[0] = byte(len(p.name))
+	n := 1
+	n += copy(buf[n:], p.name)
+	binary.LittleEndian.PutUint64(buf[n:n+8], p.size)
+	n += 8
+	copy(buf[n:], p.digest[:])
+
+	return buf
+}
+
+func decodePlotOffer(b []byte) (p plotOfferPayload, err error) {
+	if len(b) < 1 {
+		return plotOfferPayload{}, fmt.Errorf("short PLOT_OFFER payload")
+	}
+
+	nameLen := int(b[0])
+	n := 1
+	if len(b) < n+nameLen+8+digestSize {
+		return plotOfferPayload{}, fmt.Errorf("short PLOT_OFFER payload")
+	}
+
+	p.name = string(b[n : n+nameLen])
+	n += nameLen
+	p.size = binary.LittleEndian.Uint64(b[n : n+8])
+	n += 8
+	copy(p.digest[:], b[n:n+digestSize])
+
+	return p, nil
+}
+
+// plotOfferAckPayload is the server's response to a PLOT_OFFER: either an
+// acceptance with the byte offset to resume from (zero for a fresh
+// transfer), or a rejection with a human-readable reason.
+type plotOfferAckPayload struct {
+	accept bool
+	offset uint64
+	reason string
+}
+
+func encodePlotOfferAck(p plotOfferAckPayload) []byte {
+	buf := make([]byte, 1+8+len(p.reason))
+	if p.accept {
+		buf[0] = 1
+	}
+	binary.LittleEndian.PutUint64(buf[1:9], p.offset)
+	copy(buf[9:], p.reason)
+
+	return buf
+}
+
+func decodePlotOfferAck(b []byte) (p plotOfferAckPayload, err error) {
+	if len(b) < 9 {
+		return plotOfferAckPayload{}, fmt.Errorf("short PLOT_OFFER_ACK payload")
+	}
+
+	p.accept = b[0] == 1
+	p.offset = binary.LittleEndian.Uint64(b[1:9])
+	p.reason = string(b[9:])
+
+	return p, nil
+}
+
+// dataPayload carries one chunk of plot bytes plus its CRC32C checksum.
+type dataPayload struct {
+	data []byte
+	crc  uint32
+}
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+func encodeData(p dataPayload) []byte {
+	buf := make([]byte, 4+len(p.data))
+	binary.LittleEndian.PutUint32(buf[0:4], p.crc)
+	copy(buf[4:], p.data)
+
+	return buf
+}
+
+func decodeData(b []byte) (p dataPayload, err error) {
+	if len(b) < 4 {
+		return dataPayload{}, fmt.Errorf("short DATA payload")
+	}
+
+	p.crc = binary.LittleEndian.Uint32(b[0:4])
+	p.data = b[4:]
+
+	if crc32.Checksum(p.data, crc32cTable) != p.crc {
+		return dataPayload{}, fmt.Errorf("DATA chunk failed CRC32C check")
+	}
+
+	return p, nil
+}
+
+// plotDonePayload carries the digest the sender believes it just sent, for
+// a final end-to-end check against the receiver's running hash.
+type plotDonePayload struct {
+	digest [digestSize]byte
+}
+
+func encodePlotDone(p plotDonePayload) []byte {
+	buf := make([]byte, digestSize)
+	copy(buf, p.digest[:])
+	return buf
+}
+
+func decodePlotDone(b []byte) (p plotDonePayload, err error) {
+	if len(b) < digestSize {
+		return plotDonePayload{}, fmt.Errorf("short PLOT_DONE payload")
+	}
+	copy(p.digest[:], b[:digestSize])
+	return p, nil
+}
+
+// errorPayload carries a structured failure back to the other peer.
+type errorPayload struct {
+	code    errorCode
+	message string
+}
+
+func encodeError(p errorPayload) []byte {
+	buf := make([]byte, 2+len(p.message))
+	binary.LittleEndian.PutUint16(buf[0:2], uint16(p.code))
+	copy(buf[2:], p.message)
+
+	return buf
+}
+
+func decodeError(b []byte) (p errorPayload, err error) {
+	if len(b) < 2 {
+		return errorPayload{}, fmt.Errorf("short ERROR payload")
+	}
+	p.code = errorCode(binary.LittleEndian.Uint16(b[0:2]))
+	p.message = string(b[2:])
+
+	return p, nil
+}
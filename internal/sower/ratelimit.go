@@ -0,0 +1,139 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package sower
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// peerLimiters hands out a shared token-bucket limiter per remote IP, so a
+// single farmer can't saturate the harvester's NIC and starve other peers
+// connecting at the same time.
+//
+// This is deliberately a separate mechanism from internal/util/flow's
+// Manager/Monitor chain (cfg.MaxBytesPerSec, Server.MaxBytesPerSec,
+// Client.MaxBytesPerSec, Server.DestinationMaxBytesPerSec,
+// MaxReadBytesPerSec/MaxWriteBytesPerSec): flow's budgets are an operator's
+// bandwidth-shaping policy applied per transfer, inside the protocol, once
+// a peer has already been accepted; peerLimiters is a connection-layer
+// abuse guard applied to every byte of every accepted net.Conn, including
+// handshake and protocol framing, before any of that policy runs. A
+// misconfigured or malicious peer that never gets past the handshake would
+// still be unthrottled by flow's chain, which only wraps the plot payload.
+// Folding the two together would mean a transfer's byte budget could be
+// bypassed by opening more connections, or conversely that the per-peer
+// cap could be starved out by a generous transfer budget; keeping them
+// independent means cfg.Security.RateLimit is the floor no per-transfer
+// setting can raise.
+type peerLimiters struct {
+	mu         sync.Mutex
+	limiters   map[string]*rate.Limiter
+	bytesPerMB float64
+	burstMB    float64
+}
+
+func newPeerLimiters(mbPerSec float64, burstMB float64) *peerLimiters {
+	return &peerLimiters{
+		limiters:   make(map[string]*rate.Limiter),
+		bytesPerMB: mbPerSec,
+		burstMB:    burstMB,
+	}
+}
+
+func (p *peerLimiters) forAddr(addr net.Addr) *rate.Limiter {
+	host := addr.String()
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		host = tcpAddr.IP.String()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if l, ok := p.limiters[host]; ok {
+		return l
+	}
+
+	const mib = 1 << 20
+	limit := rate.Limit(p.bytesPerMB * mib)
+	burst := int(p.burstMB * mib)
+	if burst < 1 {
+		burst = 1
+	}
+
+	l := rate.NewLimiter(limit, burst)
+	p.limiters[host] = l
+
+	return l
+}
+
+// rateLimitedConn wraps a net.Conn so every Read and Write blocks until the
+// shared per-peer token bucket has capacity, capping the combined throughput
+// of both directions to the configured MB/s.
+type rateLimitedConn struct {
+	net.Conn
+	ctx     context.Context
+	limiter *rate.Limiter
+}
+
+func newRateLimitedConn(ctx context.Context, conn net.Conn, limiter *rate.Limiter) net.Conn {
+	if limiter == nil {
+		return conn
+	}
+
+	return &rateLimitedConn{Conn: conn, ctx: ctx, limiter: limiter}
+}
+
+func (c *rateLimitedConn) Read(b []byte) (n int, err error) {
+	n, err = c.Conn.Read(b)
+	if n > 0 {
+		if waitErr := c.limiter.WaitN(c.ctx, clampBurst(n, c.limiter)); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+
+	return n, err
+}
+
+func (c *rateLimitedConn) Write(b []byte) (n int, err error) {
+	if waitErr := c.limiter.WaitN(c.ctx, clampBurst(len(b), c.limiter)); waitErr != nil {
+		return 0, waitErr
+	}
+
+	return c.Conn.Write(b)
+}
+
+// clampBurst keeps WaitN from erroring out when a single Read/Write is
+// larger than the limiter's burst size; it just waits for the full burst.
+func clampBurst(n int, limiter *rate.Limiter) int {
+	if burst := limiter.Burst(); n > burst {
+		return burst
+	}
+
+	return n
+}
+
+var _ io.ReadWriter = (*rateLimitedConn)(nil)
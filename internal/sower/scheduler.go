@@ -0,0 +1,229 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package sower
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+)
+
+// ErrNoCapacity is returned by Scheduler.Reserve when no configured path
+// has enough effective free space for the requested size.
+var ErrNoCapacity = errors.New("sower: no destination path has enough free space")
+
+// ReleaseFunc gives back the space claimed by a successful Reserve call. It
+// is safe to call more than once; only the first call has any effect. It
+// must be called exactly once per Reserve, whether or not the transfer it
+// was reserved for actually succeeds.
+type ReleaseFunc func()
+
+// Policy chooses which of candidates should receive the next reservation.
+// candidates only ever contains paths that already satisfy the requested
+// size plus headroom, so Select never needs to reject on capacity.
+type Policy interface {
+	Select(candidates []*path) *path
+}
+
+// Scheduler replaces the old pathList: instead of a single package-level
+// mutex and a full re-sort on every lookup, it tracks each path's in-flight
+// reservations so two concurrent transfers can't both pick a disk that only
+// has room for one of them, and it keeps the paths ordered by effective
+// free space in a heap so picking and re-ranking a path after a
+// Reserve/Release is O(log n) instead of O(n log n).
+type Scheduler struct {
+	mu              sync.Mutex
+	paths           []*path
+	policy          Policy
+	headroomBytes   uint64
+	headroomPercent float64
+}
+
+// NewScheduler creates a Scheduler that picks destinations using policy. A
+// nil policy defaults to LeastUsedPolicy, matching the old pathList's
+// behavior of always picking the path with the most free space.
+func NewScheduler(policy Policy) (s *Scheduler) {
+	if policy == nil {
+		policy = LeastUsedPolicy{}
+	}
+
+	return &Scheduler{policy: policy}
+}
+
+// SetHeadroom configures the minimum space, in absolute bytes and/or as a
+// percentage of a path's total size, that Reserve always leaves unclaimed
+// on a path. The larger of the two applies.
+func (s *Scheduler) SetHeadroom(bytes uint64, percent float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.headroomBytes = bytes
+	s.headroomPercent = percent
+}
+
+// Populate adds paths as new destinations. Callers build each path with
+// newDestination, which picks the afero.Fs and FreeSpacer appropriate to
+// that destination's scheme.
+func (s *Scheduler) Populate(paths []*path) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range paths {
+		heap.Push(s, p)
+	}
+}
+
+// Len implements heap.Interface. Callers should use Reserve/Release/Remove
+// instead of touching the heap directly; Len is also useful on its own to
+// size the worker pool.
+func (s *Scheduler) Len() int { return len(s.paths) }
+
+// Less implements heap.Interface, ordering paths by effective free space
+// descending so the emptiest-used path is always at the root.
+func (s *Scheduler) Less(i, j int) bool { return s.paths[i].free() > s.paths[j].free() }
+
+// Swap implements heap.Interface.
+func (s *Scheduler) Swap(i, j int) {
+	s.paths[i], s.paths[j] = s.paths[j], s.paths[i]
+	s.paths[i].index = i
+	s.paths[j].index = j
+}
+
+// Push implements heap.Interface. Use Populate to add paths.
+func (s *Scheduler) Push(x any) {
+	p := x.(*path)
+	p.index = len(s.paths)
+	s.paths = append(s.paths, p)
+}
+
+// Pop implements heap.Interface. Use Remove to drop a path.
+func (s *Scheduler) Pop() any {
+	old := s.paths
+	n := len(old)
+	p := old[n-1]
+	old[n-1] = nil
+	p.index = -1
+	s.paths = old[:n-1]
+
+	return p
+}
+
+// headroomFor returns the minimum free space p must retain after a
+// reservation: the larger of the configured absolute and percentage
+// headroom.
+func (s *Scheduler) headroomFor(p *path) uint64 {
+	headroom := s.headroomBytes
+
+	if s.headroomPercent > 0 {
+		if pct := uint64(float64(p.usage.Size()) * s.headroomPercent); pct > headroom {
+			headroom = pct
+		}
+	}
+
+	return headroom
+}
+
+// Reserve atomically picks a destination path with enough effective free
+// space (current free bytes, minus any in-flight reservations, minus
+// headroom) for size, claims that space, and returns a ReleaseFunc the
+// caller must invoke exactly once when the transfer finishes, successfully
+// or not, to give the space back. It returns ErrNoCapacity if no path
+// currently qualifies; callers that want to wait for space to free up
+// should retry.
+func (s *Scheduler) Reserve(size uint64) (selected *path, release ReleaseFunc, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var candidates []*path
+	for _, p := range s.paths {
+		if p.free() >= size+s.headroomFor(p) {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil, ErrNoCapacity
+	}
+
+	selected = s.policy.Select(candidates)
+	selected.reserved += size
+	heap.Fix(s, selected.index)
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			selected.reserved -= size
+			if selected.index >= 0 {
+				heap.Fix(s, selected.index)
+			}
+		})
+	}
+
+	return selected, release, nil
+}
+
+// Update re-ranks p in the heap in O(log n) after its FreeSpacer reports
+// new numbers on its own (every backend's FreeSpacer queries live, so there
+// is nothing to refresh here beyond restoring the heap invariant). Release
+// already does this for the path it reserved; Update exists for callers
+// that want to re-rank a path independently of a Reserve/Release pair.
+func (s *Scheduler) Update(p *path) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.index >= 0 {
+		heap.Fix(s, p.index)
+	}
+}
+
+// Remove permanently drops p from the scheduler, e.g. when an operator
+// takes a destination offline. Unlike the old pathList.Remove, this
+// mutates the Scheduler's own backing slice in place.
+func (s *Scheduler) Remove(p *path) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.index < 0 || p.index >= len(s.paths) || s.paths[p.index] != p {
+		return
+	}
+
+	heap.Remove(s, p.index)
+}
+
+// FindByName returns the path whose name matches, or nil if none does, so
+// callers identifying a destination by its configured directory (or key
+// prefix) rather than its *path pointer, e.g. Sower.DropDestination, can
+// still look it up.
+func (s *Scheduler) FindByName(name string) *path {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.paths {
+		if p.name == name {
+			return p
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package sower
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tcpaddock/shiplot/internal/config"
+	"github.com/tcpaddock/shiplot/internal/transport/noise"
+)
+
+const challengeSize = 32
+
+// serverTLSConfig builds the TLS config used by TcpServer.Run when
+// cfg.Security.TLS.CertFile/KeyFile are set. When ClientCAFile is also set,
+// clients must present a certificate signed by it (mTLS).
+func serverTLSConfig(cfg config.Config) (*tls.Config, error) {
+	if cfg.Security.TLS.CertFile == "" || cfg.Security.TLS.KeyFile == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.Security.TLS.CertFile, cfg.Security.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if cfg.Security.TLS.ClientCAFile != "" {
+		pool, err := loadCertPool(cfg.Security.TLS.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsCfg, nil
+}
+
+// clientTLSConfig builds the TLS config used by TcpClient.connect when
+// cfg.Security.TLS.CertFile/KeyFile are set, trusting the server's
+// certificate against ClientCAFile (used here as the client's root CA).
+func clientTLSConfig(cfg config.Config) (*tls.Config, error) {
+	if cfg.Security.TLS.CertFile == "" || cfg.Security.TLS.KeyFile == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.Security.TLS.CertFile, cfg.Security.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if cfg.Security.TLS.ClientCAFile != "" {
+		pool, err := loadCertPool(cfg.Security.TLS.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	return tlsCfg, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+
+	return pool, nil
+}
+
+// allowedCN reports whether cn is present in cfg.Security.Auth.AllowedCNs.
+// An empty allow-list permits any CN that made it through TLS verification.
+func allowedCN(cfg config.Config, cn string) bool {
+	if len(cfg.Security.Auth.AllowedCNs) == 0 {
+		return true
+	}
+
+	for _, allowed := range cfg.Security.Auth.AllowedCNs {
+		if strings.EqualFold(allowed, cn) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func loadSharedToken(cfg config.Config) ([]byte, error) {
+	if cfg.Security.Auth.SharedTokenFile == "" {
+		return nil, nil
+	}
+
+	b, err := os.ReadFile(cfg.Security.Auth.SharedTokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSpace(b), nil
+}
+
+// serverChallenge issues a random nonce and verifies the HMAC-SHA256(token,
+// nonce) response, rejecting the connection if the shared token is
+// configured but the peer can't prove it knows it.
+func serverChallenge(conn io.ReadWriter, token []byte) (err error) {
+	if len(token) == 0 {
+		return nil
+	}
+
+	nonce := make([]byte, challengeSize)
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+	if _, err = conn.Write(nonce); err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, token)
+	mac.Write(nonce)
+	want := mac.Sum(nil)
+
+	got := make([]byte, len(want))
+	if _, err = io.ReadFull(conn, got); err != nil {
+		return err
+	}
+
+	if !hmac.Equal(want, got) {
+		return fmt.Errorf("peer failed shared-token challenge")
+	}
+
+	return nil
+}
+
+// clientRespond reads the server's nonce and answers with HMAC-SHA256(token,
+// nonce), proving knowledge of the shared token without sending it.
+func clientRespond(conn io.ReadWriter, token []byte) (err error) {
+	if len(token) == 0 {
+		return nil
+	}
+
+	nonce := make([]byte, challengeSize)
+	if _, err = io.ReadFull(conn, nonce); err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha256.New, token)
+	mac.Write(nonce)
+
+	_, err = conn.Write(mac.Sum(nil))
+	return err
+}
+
+// serverNoiseIdentity loads the server's static Noise identity and
+// authorized-keys list from cfg.Server.IdentityKeyFile/AuthorizedKeysFile.
+// It returns ok=false when IdentityKeyFile is unset, meaning the Noise
+// transport isn't in use.
+func serverNoiseIdentity(cfg config.Config) (identity noise.Keypair, authorizedKeys [][]byte, ok bool, err error) {
+	if cfg.Server.IdentityKeyFile == "" {
+		return noise.Keypair{}, nil, false, nil
+	}
+
+	identity, err = noise.LoadKeypair(cfg.Server.IdentityKeyFile)
+	if err != nil {
+		return noise.Keypair{}, nil, false, err
+	}
+
+	authorizedKeys, err = noise.LoadAuthorizedKeys(cfg.Server.AuthorizedKeysFile)
+	if err != nil {
+		return noise.Keypair{}, nil, false, err
+	}
+
+	return identity, authorizedKeys, true, nil
+}
+
+// clientNoiseIdentity loads the client's static Noise identity and the
+// server's known public key from cfg.Client.IdentityKeyFile/ServerKeyFile.
+// It returns ok=false when IdentityKeyFile is unset, meaning the Noise
+// transport isn't in use.
+func clientNoiseIdentity(cfg config.Config) (identity noise.Keypair, serverKey []byte, ok bool, err error) {
+	if cfg.Client.IdentityKeyFile == "" {
+		return noise.Keypair{}, nil, false, nil
+	}
+
+	identity, err = noise.LoadKeypair(cfg.Client.IdentityKeyFile)
+	if err != nil {
+		return noise.Keypair{}, nil, false, err
+	}
+
+	serverKey, err = noise.LoadPublicKey(cfg.Client.ServerKeyFile)
+	if err != nil {
+		return noise.Keypair{}, nil, false, err
+	}
+
+	return identity, serverKey, true, nil
+}
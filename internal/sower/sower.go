@@ -22,7 +22,9 @@ THE SOFTWARE.
 package sower
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -32,17 +34,53 @@ import (
 	"time"
 
 	"github.com/panjf2000/ants/v2"
+	"github.com/spf13/afero"
 	"github.com/tcpaddock/shiplot/internal/config"
+	"github.com/tcpaddock/shiplot/internal/progress"
+	"github.com/tcpaddock/shiplot/internal/sower/contenthash"
 	"github.com/tcpaddock/shiplot/internal/util"
+	"github.com/tcpaddock/shiplot/internal/util/flow"
+	"github.com/tcpaddock/shiplot/internal/wire"
+	"github.com/zeebo/blake3"
+	"golang.org/x/crypto/blake2b"
 	"golang.org/x/exp/slog"
 )
 
 type Sower struct {
 	cfg    config.Config
-	paths  *pathList
-	pool   *ants.Pool
-	wg     sync.WaitGroup
 	client *TcpClient
+
+	scheduler *Scheduler
+	destPaths []string
+
+	// globalManager, serverManager, clientManager and destManagers enforce
+	// cfg.MaxBytesPerSec, cfg.Server.MaxBytesPerSec, cfg.Client.MaxBytesPerSec
+	// and cfg.Server.DestinationMaxBytesPerSec respectively; a transfer is
+	// throttled by all of the ones that apply to it at once.
+	globalManager *flow.Manager
+	serverManager *flow.Manager
+	clientManager *flow.Manager
+	destManagers  map[string]*flow.Manager
+
+	transfersMu sync.Mutex
+	transfers   map[string]*transfer
+
+	progress *progress.Writer
+
+	// contentHashes caches each moved plot's BLAKE2b-256 digest by absolute
+	// destination path, so a later rescan doesn't have to re-read the
+	// whole file to confirm it's still intact.
+	contentHashes *contenthash.Cache
+
+	// pauseMu, paused and resumeCond gate waitIfPaused; Pause/Resume flip
+	// paused and, on Resume, wake every worker blocked waiting for it to
+	// clear.
+	pauseMu    sync.Mutex
+	paused     bool
+	resumeCond *sync.Cond
+
+	pool *ants.Pool
+	wg   sync.WaitGroup
 }
 
 func NewSower(cfg config.Config) (s *Sower, err error) {
@@ -50,12 +88,35 @@ func NewSower(cfg config.Config) (s *Sower, err error) {
 
 	s.cfg = cfg
 	s.client = NewTcpClient(cfg)
+	s.progress = progress.NewWriter()
+	s.client.progress = s.progress
+	s.contentHashes = contenthash.NewCache()
+	s.resumeCond = sync.NewCond(&s.pauseMu)
 
-	// Fill list of available destination paths
+	if cfg.MaxBytesPerSec > 0 {
+		s.globalManager = flow.NewManager(cfg.MaxBytesPerSec)
+	}
+	if cfg.Server.MaxBytesPerSec > 0 {
+		s.serverManager = flow.NewManager(cfg.Server.MaxBytesPerSec)
+	}
+	if cfg.Client.MaxBytesPerSec > 0 {
+		s.clientManager = flow.NewManager(cfg.Client.MaxBytesPerSec)
+	}
+	s.destManagers = make(map[string]*flow.Manager, len(cfg.Server.DestinationMaxBytesPerSec))
+	for name, maxBytesPerSec := range cfg.Server.DestinationMaxBytesPerSec {
+		if maxBytesPerSec > 0 {
+			s.destManagers[name] = flow.NewManager(maxBytesPerSec)
+		}
+	}
+	s.transfers = make(map[string]*transfer)
+
+	// Fill list of available destination paths. Glob expansion only applies
+	// to local paths; a remote URL's "*" (if any) is part of a bucket name
+	// or key prefix, not a filesystem glob.
 	var destPaths []string
 
 	for _, destPath := range s.cfg.DestinationPaths {
-		if strings.Contains(destPath, "*") {
+		if strings.Contains(destPath, "*") && !strings.Contains(destPath, "://") {
 			globPaths, err := filepath.Glob(destPath)
 			if err != nil {
 				return nil, err
@@ -66,8 +127,21 @@ func NewSower(cfg config.Config) (s *Sower, err error) {
 			destPaths = append(destPaths, destPath)
 		}
 	}
-	s.paths = new(pathList)
-	s.paths.Populate(destPaths)
+	s.destPaths = destPaths
+
+	destinations := make([]*path, 0, len(destPaths))
+	for _, destPath := range destPaths {
+		d, err := newDestination(destPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure destination %q: %w", destPath, err)
+		}
+
+		destinations = append(destinations, d)
+	}
+
+	s.scheduler = NewScheduler(schedulerPolicy(cfg.Server.SchedulerPolicy))
+	s.scheduler.SetHeadroom(cfg.Server.HeadroomBytes, cfg.Server.HeadroomPercent)
+	s.scheduler.Populate(destinations)
 
 	// Create worker pool for moving plots from stream
 	size := s.getPoolSize()
@@ -82,14 +156,20 @@ func NewSower(cfg config.Config) (s *Sower, err error) {
 
 func (s *Sower) enqueuePlotMove(ctx context.Context, name string) (err error) {
 	s.wg.Add(1)
+	s.publishProgress(progress.Vertex{Name: filepath.Base(name), Status: progress.StatusQueued})
 	err = s.pool.Submit(func() {
 		defer s.wg.Done()
+		s.waitIfPaused()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
 
 		// Open source file
 		src, err := os.Open(name)
 		if err != nil {
 			src.Close()
 			slog.Default().Error(fmt.Sprintf("failed to open %s", name), err)
+			s.publishProgress(progress.Vertex{Name: filepath.Base(name), Status: progress.StatusError, Error: err.Error()})
 			return
 		}
 
@@ -97,12 +177,18 @@ func (s *Sower) enqueuePlotMove(ctx context.Context, name string) (err error) {
 		info, err := src.Stat()
 		if err != nil {
 			slog.Default().Error(fmt.Sprintf("failed to get file info %s", name), err)
+			s.publishProgress(progress.Vertex{Name: filepath.Base(name), Status: progress.StatusError, Error: err.Error()})
 			return
 		}
 
 		// Find the best destination path
-		dstPath := s.getDestinationPath(uint64(info.Size()))
-		defer s.paths.SetAvailable(dstPath, true)
+		dstPath, release, err := s.reserveDestination(uint64(info.Size()))
+		if err != nil {
+			slog.Default().Error(fmt.Sprintf("failed to reserve a destination for %s", name), err)
+			s.publishProgress(progress.Vertex{Name: filepath.Base(name), Status: progress.StatusError, Total: uint64(info.Size()), Error: err.Error()})
+			return
+		}
+		defer release()
 
 		var (
 			dstDir      = dstPath.name
@@ -112,24 +198,53 @@ func (s *Sower) enqueuePlotMove(ctx context.Context, name string) (err error) {
 		slog.Default().Info(fmt.Sprintf("Moving %s to %s", filepath.Base(src.Name()), dstDir))
 
 		// Create destination file
-		dst, err := os.Create(dstFullName + ".tmp")
+		dst, err := dstPath.fs.Create(dstFullName + ".tmp")
 		if err != nil {
 			slog.Default().Error(fmt.Sprintf("failed to create temp destination file %s", dstFullName+".tmp"), err)
 		}
 
+		managers := s.downloadManagers(dstPath)
+		if m := s.maxReadManager(); m != nil {
+			managers = append(managers, m)
+		}
+		if m := s.maxWriteManager(); m != nil {
+			managers = append(managers, m)
+		}
+		monitor := flow.NewMonitor(managers...)
+		endTransfer := s.startTransfer(filepath.Base(name), uint64(info.Size()), monitor, cancel)
+		defer endTransfer()
+
+		s.publishProgress(progress.Vertex{Name: filepath.Base(name), Status: progress.StatusTransferring, Total: uint64(info.Size())})
+
 		start := time.Now()
 
-		// Copy plot
-		cr := util.NewContextReader(ctx, src)
-		cw := util.NewContextWriter(ctx, dst)
-		written, err := io.Copy(cw, cr)
+		// Copy plot, throttled by monitor (the destination/server/global
+		// budgets plus cfg.MaxReadBytesPerSec/MaxWriteBytesPerSec, if
+		// configured) while teeing the plaintext through the same
+		// BLAKE2b-256 hash contenthash uses, so it can be checked against
+		// the bytes actually landed on disk once the move completes.
+		h, err := blake2b.New256(nil)
+		if err != nil {
+			slog.Default().Error("failed to initialize content hash", err)
+		}
+		copyReader := io.TeeReader(flow.NewReader(util.NewContextReader(ctx, src), monitor), h)
+		copyWriter := util.NewContextWriter(ctx, dst)
+
+		stopPeriodic := s.publishPeriodicProgress(filepath.Base(name), uint64(info.Size()), monitor)
+		written, err := io.Copy(copyWriter, copyReader)
+		stopPeriodic()
 		if err != nil {
 			slog.Default().Error(fmt.Sprintf("failed to copy %s to %s", name, filepath.Base(dst.Name())), err)
 		}
 
 		if uint64(written) != uint64(info.Size()) {
-			os.Remove(dstFullName + ".tmp")
-			slog.Default().Error(fmt.Sprintf("failed to copy %s to %s", name, filepath.Base(dst.Name())), fmt.Errorf("file size mismatch"))
+			dstPath.fs.Remove(dstFullName + ".tmp")
+			err = fmt.Errorf("file size mismatch")
+			slog.Default().Error(fmt.Sprintf("failed to copy %s to %s", name, filepath.Base(dst.Name())), err)
+		}
+		if err != nil {
+			s.publishProgress(progress.Vertex{Name: filepath.Base(name), Status: progress.StatusError, Total: uint64(info.Size()), Written: uint64(written), Error: err.Error()})
+			return
 		}
 
 		// Windows requires closing files before rename
@@ -137,23 +252,37 @@ func (s *Sower) enqueuePlotMove(ctx context.Context, name string) (err error) {
 		dst.Close()
 
 		// Rename temporary file
-		err = os.Rename(dstFullName+".tmp", dstFullName)
+		err = dstPath.fs.Rename(dstFullName+".tmp", dstFullName)
 		if err != nil {
 			slog.Default().Error(fmt.Sprintf("failed to rename temp file %s", dstFullName+".tmp"), err)
+			s.publishProgress(progress.Vertex{Name: filepath.Base(name), Status: progress.StatusError, Total: uint64(info.Size()), Written: uint64(written), Error: err.Error()})
+			return
+		}
+		// writePlotSidecar shells out to the real filesystem directly, so it
+		// only applies to local destinations until it's taught to go
+		// through an afero.Fs too.
+		if _, local := dstPath.fs.(*afero.OsFs); local {
+			writePlotSidecar(dstFullName)
 		}
 
 		duration := time.Since(start)
 
-		// Delete source file
-		err = os.Remove(src.Name())
-		if err != nil {
-			slog.Default().Error(fmt.Sprintf("failed to delete file %s", src.Name()), err)
-		}
+		var sourceDigest contenthash.Digest
+		copy(sourceDigest[:], h.Sum(nil))
+		s.contentHashes.Set(dstFullName, sourceDigest)
+
+		// Re-hash the renamed file from disk and delete the source only
+		// once that's confirmed to match what was actually copied, rather
+		// than assuming a successful io.Copy means the destination is
+		// intact. A mismatch quarantines the destination as a ".corrupt"
+		// sibling instead, so the source isn't deleted out from under a
+		// plot that turned out corrupt.
+		s.wg.Add(1)
+		go s.verifyMovedPlot(dstPath.fs, src.Name(), dstFullName, sourceDigest)
 
-		// Update available paths
-		s.paths.SetAvailable(dstPath, true)
+		s.publishProgress(progress.Vertex{Name: filepath.Base(name), Status: progress.StatusDone, Total: uint64(info.Size()), Written: uint64(written)})
 
-		slog.Default().Info(fmt.Sprintf("Moved %s to %s", name, dstDir), slog.Int64("written", written), slog.Duration("time", duration))
+		slog.Default().Info(fmt.Sprintf("Moved %s to %s", name, dstDir), slog.Int64("written", written), slog.Duration("time", duration), slog.Float64("rateBps", averageRate(written, duration)))
 	})
 	if err != nil {
 		return err
@@ -162,69 +291,114 @@ func (s *Sower) enqueuePlotMove(ctx context.Context, name string) (err error) {
 	return nil
 }
 
-func (s *Sower) enqueuePlotDownload(ctx context.Context, name string, size uint64, reader io.Reader, writer io.Writer) (err error) {
+// verifyMovedPlot re-hashes dstFullName from fs and compares it against
+// sourceDigest, the BLAKE2b-256 digest computed while the plot was being
+// copied. A match deletes srcFullName, completing the move; a mismatch logs the
+// failure and quarantines dstFullName by renaming it to a ".corrupt"
+// sibling, leaving srcFullName in place so the plot isn't lost.
+func (s *Sower) verifyMovedPlot(fs afero.Fs, srcFullName string, dstFullName string, sourceDigest contenthash.Digest) {
+	defer s.wg.Done()
+
+	ok, err := contenthash.VerifyFs(fs, dstFullName, sourceDigest)
+	if err != nil {
+		slog.Default().Error(fmt.Sprintf("failed to verify %s", dstFullName), err)
+		return
+	}
+
+	if !ok {
+		s.contentHashes.Delete(dstFullName)
+		corruptName := dstFullName + ".corrupt"
+		if err := fs.Rename(dstFullName, corruptName); err != nil {
+			slog.Default().Error(fmt.Sprintf("failed to quarantine corrupt plot %s", dstFullName), err)
+			return
+		}
+		slog.Default().Error(fmt.Sprintf("content hash mismatch after moving %s, quarantined as %s", dstFullName, corruptName), errors.New("content hash mismatch"))
+		return
+	}
+
+	if err := os.Remove(srcFullName); err != nil {
+		slog.Default().Error(fmt.Sprintf("failed to delete file %s", srcFullName), err)
+	}
+}
+
+// enqueuePlotDownload receives a plot from a connected TcpClient. A zero
+// digest means the legacy bare protocol is in use (no end-to-end
+// verification or resume); otherwise the framed protocol's PLOT_OFFER_ACK,
+// DATA, and PLOT_DONE exchange is driven from here once a destination path
+// has been chosen, since that's the point we know whether a partial
+// ".tmp" file already exists to resume from.
+func (s *Sower) enqueuePlotDownload(ctx context.Context, name string, size uint64, digest [digestSize]byte, resume bool, reader io.Reader, writer io.Writer) (err error) {
 	s.wg.Add(1)
+	s.publishProgress(progress.Vertex{Name: name, Status: progress.StatusQueued, Total: size})
 	err = s.pool.Submit(func() {
 		defer s.wg.Done()
+		s.waitIfPaused()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
 
 		// Find the best destination path
-		dstPath := s.getDestinationPath(size)
-		defer s.paths.SetAvailable(dstPath, true)
+		dstPath, release, err := s.reserveDestination(size)
+		if err != nil {
+			slog.Default().Error(fmt.Sprintf("failed to reserve a destination for %s", name), err)
+			s.publishProgress(progress.Vertex{Name: name, Status: progress.StatusError, Total: size, Error: err.Error()})
+			return
+		}
+		defer release()
 
 		var (
 			dstDir      = dstPath.name
 			dstFullName = filepath.Join(dstDir, name)
+			tmpName     = dstFullName + ".tmp"
 		)
 
 		slog.Default().Info("Downloading plot", slog.String("name", name), slog.String("destination", dstDir))
 
-		// Create destination file
-		dst, err := os.Create(dstFullName + ".tmp")
-		if err != nil {
-			_, _ = writeFail(ctx, writer)
-			dst.Close()
-			slog.Default().Error(fmt.Sprintf("failed to create temp destination file %s", dstFullName+".tmp"), err)
-			return
+		managers := s.downloadManagers(dstPath)
+		if m := s.maxReadManager(); m != nil {
+			managers = append(managers, m)
 		}
+		monitor := flow.NewMonitor(managers...)
+		endTransfer := s.startTransfer(name, size, monitor, cancel)
+		defer endTransfer()
+		reader = flow.NewReader(reader, monitor)
+
+		s.publishProgress(progress.Vertex{Name: name, Status: progress.StatusTransferring, Total: size})
 
 		start := time.Now()
 
-		// Download plot to temporary file
-		cr := util.NewContextReader(ctx, reader)
-		cw := util.NewContextWriter(ctx, dst)
-		written, err := io.Copy(cw, cr)
+		stopPeriodic := s.publishPeriodicProgress(name, size, monitor)
+
+		var written int64
+		if digest == ([digestSize]byte{}) {
+			written, err = s.legacyDownloadPlot(ctx, dstPath.fs, name, tmpName, size, reader, writer)
+		} else {
+			written, err = s.framedDownloadPlot(ctx, dstPath.fs, name, tmpName, size, digest, resume, reader, writer)
+		}
+		stopPeriodic()
 		if err != nil {
-			_, _ = writeFail(ctx, writer)
 			slog.Default().Error(fmt.Sprintf("failed to download %s", name), err)
+			s.publishProgress(progress.Vertex{Name: name, Status: progress.StatusError, Total: size, Written: uint64(written), Error: err.Error()})
 			return
 		}
 
-		if uint64(written) != size {
-			_, _ = writeFail(ctx, writer)
-			os.Remove(dstFullName + ".tmp")
-			slog.Default().Error(fmt.Sprintf("failed to download %s", name), fmt.Errorf("file size mismatch"))
-			return
-		}
-
-		_, _ = writeSuccess(ctx, writer)
-
-		// Windows requires closing files before rename
-		dst.Close()
-
 		// Rename temporary file
-		err = os.Rename(dstFullName+".tmp", dstFullName)
+		err = dstPath.fs.Rename(tmpName, dstFullName)
 		if err != nil {
-			_, _ = writeFail(ctx, writer)
-			slog.Default().Error(fmt.Sprintf("failed to rename temp file %s", dstFullName+".tmp"), err)
+			slog.Default().Error(fmt.Sprintf("failed to rename temp file %s", tmpName), err)
+			s.publishProgress(progress.Vertex{Name: name, Status: progress.StatusError, Total: size, Written: uint64(written), Error: err.Error()})
 			return
 		}
+		removeJournal(tmpName)
+		if _, local := dstPath.fs.(*afero.OsFs); local {
+			writePlotSidecar(dstFullName)
+		}
 
 		duration := time.Since(start)
 
-		// Update available paths
-		s.paths.SetAvailable(dstPath, true)
+		s.publishProgress(progress.Vertex{Name: name, Status: progress.StatusDone, Total: size, Written: uint64(written)})
 
-		slog.Default().Info(fmt.Sprintf("Downloaded %s to %s", name, dstDir), slog.Int64("written", written), slog.Duration("time", duration))
+		slog.Default().Info(fmt.Sprintf("Downloaded %s to %s", name, dstDir), slog.Int64("written", written), slog.Duration("time", duration), slog.Float64("rateBps", averageRate(written, duration)))
 	})
 	if err != nil {
 		return err
@@ -233,10 +407,189 @@ func (s *Sower) enqueuePlotDownload(ctx context.Context, name string, size uint6
 	return nil
 }
 
+// legacyDownloadPlot speaks the original bare protocol over internal/wire:
+// read chunked PAYLOAD frames into tmpName while hashing them with
+// BLAKE2b-256, until the sender's TRAILER frame carrying its digest
+// arrives, then compare before reporting status. A digest mismatch deletes
+// the partial file and reports wire.StatusDigestMismatch instead of
+// wire.StatusFail, so the client can tell a corrupted transfer apart from
+// one that failed outright and is pointless to retry as-is.
+func (s *Sower) legacyDownloadPlot(ctx context.Context, fs afero.Fs, name string, tmpName string, size uint64, reader io.Reader, writer io.Writer) (written int64, err error) {
+	dst, err := fs.Create(tmpName)
+	if err != nil {
+		_ = wire.WriteError(ctx, writer, wire.StatusFail, err.Error())
+		return 0, err
+	}
+	defer dst.Close()
+
+	h, err := blake2b.New256(nil)
+	if err != nil {
+		_ = wire.WriteError(ctx, writer, wire.StatusFail, err.Error())
+		return 0, err
+	}
+
+	var trailer []byte
+	for trailer == nil {
+		frameType, value, readErr := wire.ReadFrame(ctx, reader)
+		if readErr != nil {
+			_ = wire.WriteError(ctx, writer, wire.StatusFail, readErr.Error())
+			fs.Remove(tmpName)
+			return 0, readErr
+		}
+
+		switch frameType {
+		case wire.FramePayload:
+			h.Write(value)
+			n, writeErr := dst.Write(value)
+			written += int64(n)
+			if writeErr != nil {
+				_ = wire.WriteError(ctx, writer, wire.StatusFail, writeErr.Error())
+				fs.Remove(tmpName)
+				return 0, writeErr
+			}
+		case wire.FrameTrailer:
+			trailer = value
+		default:
+			err = fmt.Errorf("unexpected frame type %d", frameType)
+			_ = wire.WriteError(ctx, writer, wire.StatusBadRequest, err.Error())
+			fs.Remove(tmpName)
+			return 0, err
+		}
+	}
+
+	if uint64(written) != size {
+		err = fmt.Errorf("file size mismatch")
+		_ = wire.WriteError(ctx, writer, wire.StatusFail, err.Error())
+		fs.Remove(tmpName)
+		return 0, err
+	}
+
+	s.publishProgress(progress.Vertex{Name: name, Status: progress.StatusVerifying, Total: size, Written: uint64(written)})
+
+	if !bytes.Equal(trailer, h.Sum(nil)) {
+		_ = wire.WriteError(ctx, writer, wire.StatusDigestMismatch, fmt.Sprintf("digest mismatch for %s", tmpName))
+		fs.Remove(tmpName)
+		return 0, fmt.Errorf("digest mismatch for %s", tmpName)
+	}
+
+	_ = wire.WriteError(ctx, writer, wire.StatusOK, "")
+
+	return written, nil
+}
+
+// framedDownloadPlot drives the server side of the framed PLOT_OFFER_ACK /
+// DATA / PLOT_DONE exchange: it checks tmpName's journal for a prior
+// attempt at the same digest, acks with the offset to resume from, reads
+// CRC32C-checked DATA frames into the file, and verifies the sender's
+// blake3 digest before accepting the plot.
+func (s *Sower) framedDownloadPlot(ctx context.Context, fs afero.Fs, name string, tmpName string, size uint64, digest [digestSize]byte, resumeSupported bool, reader io.Reader, writer io.Writer) (written int64, err error) {
+	var offset uint64
+	if resumeSupported {
+		if j, ok := readJournal(tmpName, digest); ok && j.BytesReceived <= size {
+			offset = j.BytesReceived
+		}
+	}
+	if offset == 0 {
+		fs.Remove(tmpName)
+	}
+
+	flags := os.O_RDWR | os.O_CREATE
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	dst, err := fs.OpenFile(tmpName, flags, 0644)
+	if err != nil {
+		_ = writeFrame(ctx, writer, msgPlotOfferAck, encodePlotOfferAck(plotOfferAckPayload{accept: false, reason: err.Error()}))
+		return 0, err
+	}
+	defer dst.Close()
+
+	hasher := blake3.New()
+	if offset > 0 {
+		if _, err = io.CopyN(hasher, io.NewSectionReader(dst, 0, int64(offset)), int64(offset)); err != nil {
+			offset = 0
+			hasher.Reset()
+			if _, err = dst.Seek(0, io.SeekStart); err != nil {
+				return 0, err
+			}
+			if err = dst.Truncate(0); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err = writeFrame(ctx, writer, msgPlotOfferAck, encodePlotOfferAck(plotOfferAckPayload{accept: true, offset: offset})); err != nil {
+		return 0, err
+	}
+
+	if _, err = dst.Seek(int64(offset), io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	received := offset
+	for received < size {
+		h, payload, err := readFrame(ctx, reader)
+		if err != nil {
+			return 0, err
+		}
+		if h.msgType != msgData {
+			return 0, fmt.Errorf("expected DATA frame, got message type %d", h.msgType)
+		}
+
+		data, err := decodeData(payload)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err = dst.Write(data.data); err != nil {
+			return 0, err
+		}
+		if _, err = hasher.Write(data.data); err != nil {
+			return 0, err
+		}
+
+		received += uint64(len(data.data))
+		_ = writeJournal(tmpName, digest, received)
+	}
+
+	h, payload, err := readFrame(ctx, reader)
+	if err != nil {
+		return 0, err
+	}
+	if h.msgType != msgPlotDone {
+		return 0, fmt.Errorf("expected PLOT_DONE frame, got message type %d", h.msgType)
+	}
+	done, err := decodePlotDone(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	var sum [digestSize]byte
+	copy(sum[:], hasher.Sum(nil))
+
+	s.publishProgress(progress.Vertex{Name: name, Status: progress.StatusVerifying, Total: size, Written: received})
+
+	if sum != digest || done.digest != digest {
+		_ = writeFrame(ctx, writer, msgError, encodeError(errorPayload{code: errDigestMismatch, message: "digest mismatch"}))
+		return 0, fmt.Errorf("digest mismatch for %s", tmpName)
+	}
+
+	if err = writeFrame(ctx, writer, msgPlotDone, encodePlotDone(plotDonePayload{digest: sum})); err != nil {
+		return 0, err
+	}
+
+	return int64(received), nil
+}
+
 func (s *Sower) enqueuePlotUpload(ctx context.Context, name string) (err error) {
 	s.wg.Add(1)
 	err = s.pool.Submit(func() {
 		defer s.wg.Done()
+		s.waitIfPaused()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
 		slog.Default().Info(fmt.Sprintf("Uploading %s", name))
 
 		// Open source file
@@ -254,11 +607,19 @@ func (s *Sower) enqueuePlotUpload(ctx context.Context, name string) (err error)
 			return
 		}
 
+		managers := s.uploadManagers()
+		if m := s.maxWriteManager(); m != nil {
+			managers = append(managers, m)
+		}
+		monitor := flow.NewMonitor(managers...)
+		endTransfer := s.startTransfer(filepath.Base(name), uint64(info.Size()), monitor, cancel)
+		defer endTransfer()
+
 		start := time.Now()
 
-		// Upload plot file
-		cr := util.NewContextReader(ctx, src)
-		written, err := s.client.WritePlot(ctx, filepath.Base(name), uint64(info.Size()), cr)
+		// Upload plot file; WritePlot needs to seek src to hash it up front
+		// and, on a resumed transfer, to continue partway through.
+		written, err := s.client.WritePlot(ctx, filepath.Base(name), uint64(info.Size()), src, monitor)
 		if err != nil {
 			slog.Default().Error(fmt.Sprintf("failed to upload %s", name), err)
 			return
@@ -275,7 +636,7 @@ func (s *Sower) enqueuePlotUpload(ctx context.Context, name string) (err error)
 			slog.Default().Error(fmt.Sprintf("failed to delete file %s", src.Name()), err)
 		}
 
-		slog.Default().Info(fmt.Sprintf("Successfully uploaded %s", name), slog.Int64("written", written), slog.Duration("time", duration))
+		slog.Default().Info(fmt.Sprintf("Successfully uploaded %s", name), slog.Int64("written", written), slog.Duration("time", duration), slog.Float64("rateBps", averageRate(written, duration)))
 	})
 	if err != nil {
 		return err
@@ -284,38 +645,94 @@ func (s *Sower) enqueuePlotUpload(ctx context.Context, name string) (err error)
 	return nil
 }
 
-func (s *Sower) getDestinationPath(fileSize uint64) (destinationPath *path) {
-	// Find the best destination path
-	var dstPath *path
-
+// reserveDestination picks a destination path with enough effective free
+// space for fileSize, retrying every 10 seconds if every path is either
+// full or already claimed by another in-flight transfer. The caller must
+// invoke the returned ReleaseFunc exactly once when it's done with the
+// path, successfully or not.
+func (s *Sower) reserveDestination(fileSize uint64) (dstPath *path, release ReleaseFunc, err error) {
 	for {
-		// Get the lowest sized first path and mark it unavailable
-		dstPath = s.paths.FirstAvailable()
-
-		// Wait for 10 seconds if no available destination
-		if dstPath == nil {
-			time.Sleep(time.Second * 10)
-			continue
+		dstPath, release, err = s.scheduler.Reserve(fileSize)
+		if err == nil {
+			return dstPath, release, nil
 		}
-
-		// Ensure destination path has enough space
-		if uint64(fileSize) < dstPath.usage.Free() {
-			break
-		} else {
-			// Remove path if space is too low
-			s.paths.Remove(dstPath)
-
-			// Adjust move pool
-			size := s.getPoolSize()
-			if s.pool.Cap() != size {
-				slog.Default().Info(fmt.Sprintf("Adjusting worker pool max size to %d", size))
-				s.pool.Tune(size)
-			}
-			continue
+		if !errors.Is(err, ErrNoCapacity) {
+			return nil, nil, err
 		}
+
+		time.Sleep(time.Second * 10)
+	}
+}
+
+// downloadManagers returns the chain of flow.Managers that apply to a plot
+// arriving over the receive path (move or download), from most to least
+// specific: the destination's own budget, the server-wide receive budget,
+// then the global budget.
+func (s *Sower) downloadManagers(dstPath *path) (managers []*flow.Manager) {
+	if m, ok := s.destManagers[dstPath.name]; ok {
+		managers = append(managers, m)
+	}
+	if s.serverManager != nil {
+		managers = append(managers, s.serverManager)
+	}
+	if s.globalManager != nil {
+		managers = append(managers, s.globalManager)
 	}
 
-	return dstPath
+	return managers
+}
+
+// uploadManagers returns the chain of flow.Managers that apply to a plot
+// being sent to a remote server: the client-wide send budget, then the
+// global budget. The server-side budgets don't apply to outbound transfers.
+func (s *Sower) uploadManagers() (managers []*flow.Manager) {
+	if s.clientManager != nil {
+		managers = append(managers, s.clientManager)
+	}
+	if s.globalManager != nil {
+		managers = append(managers, s.globalManager)
+	}
+
+	return managers
+}
+
+// maxReadManager returns a flow.Manager enforcing cfg.MaxReadBytesPerSec, or
+// nil if it's unset, for appending onto a transfer's manager chain wherever
+// it reads incoming bytes (a move's source file, a download's network
+// stream).
+func (s *Sower) maxReadManager() *flow.Manager {
+	if s.cfg.MaxReadBytesPerSec <= 0 {
+		return nil
+	}
+
+	return flow.NewManager(uint64(s.cfg.MaxReadBytesPerSec))
+}
+
+// maxWriteManager returns a flow.Manager enforcing cfg.MaxWriteBytesPerSec,
+// or nil if it's unset, for appending onto a transfer's manager chain
+// wherever it writes outgoing bytes (a move's destination file, an upload's
+// network stream).
+func (s *Sower) maxWriteManager() *flow.Manager {
+	if s.cfg.MaxWriteBytesPerSec <= 0 {
+		return nil
+	}
+
+	return flow.NewManager(uint64(s.cfg.MaxWriteBytesPerSec))
+}
+
+// schedulerPolicy maps cfg.Server.SchedulerPolicy to a Policy, defaulting
+// to LeastUsedPolicy when unset or unrecognized.
+func schedulerPolicy(name string) Policy {
+	switch name {
+	case "round_robin":
+		return &RoundRobinPolicy{}
+	case "weighted_free_space":
+		return WeightedByFreeSpacePolicy{}
+	case "mru":
+		return &MRUPolicy{}
+	default:
+		return LeastUsedPolicy{}
+	}
 }
 
 func (s *Sower) getPoolSize() (size int) {
@@ -326,10 +743,10 @@ func (s *Sower) getPoolSize() (size int) {
 	}
 
 	if poolSize == 0 {
-		poolSize = s.paths.Len()
+		poolSize = s.scheduler.Len()
 	} else {
-		if s.paths.Len() < poolSize {
-			poolSize = s.paths.Len()
+		if s.scheduler.Len() < poolSize {
+			poolSize = s.scheduler.Len()
 		}
 	}
 
@@ -340,14 +757,3 @@ func (s *Sower) getPoolSize() (size int) {
 	return poolSize
 }
 
-func writeSuccess(ctx context.Context, writer io.Writer) (written int, err error) {
-	cw := util.NewContextWriter(ctx, writer)
-
-	return cw.Write([]byte{1})
-}
-
-func writeFail(ctx context.Context, writer io.Writer) (written int, err error) {
-	cw := util.NewContextWriter(ctx, writer)
-
-	return cw.Write([]byte{0})
-}
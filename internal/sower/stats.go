@@ -0,0 +1,167 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package sower
+
+import (
+	"context"
+	"time"
+
+	"github.com/tcpaddock/shiplot/internal/progress"
+	"github.com/tcpaddock/shiplot/internal/util/flow"
+)
+
+// periodicProgressInterval is how often publishPeriodicProgress
+// republishes a transfer's status while it's in flight.
+const periodicProgressInterval = 2 * time.Second
+
+// transfer tracks a single in-flight move, download or upload so its
+// progress and throughput can be reported via Sower.Stats, and so it can be
+// stopped on demand via Sower.CancelJob. cancel cancels the context the
+// transfer's copy/network operations were started with; it's a no-op once
+// the transfer has already finished.
+type transfer struct {
+	name    string
+	total   uint64
+	monitor *flow.Monitor
+	cancel  context.CancelFunc
+}
+
+// TransferStats is a point-in-time snapshot of an in-flight transfer.
+type TransferStats struct {
+	Name    string
+	Written uint64
+	Total   uint64
+	RateBps float64
+	ETA     time.Duration
+}
+
+// startTransfer registers a transfer under name so it shows up in Stats and
+// can be stopped with CancelJob, and returns a func that removes it again.
+// The caller must invoke the returned func exactly once when the transfer
+// finishes, successfully or not.
+func (s *Sower) startTransfer(name string, total uint64, monitor *flow.Monitor, cancel context.CancelFunc) func() {
+	s.transfersMu.Lock()
+	s.transfers[name] = &transfer{name: name, total: total, monitor: monitor, cancel: cancel}
+	s.transfersMu.Unlock()
+
+	return func() {
+		s.transfersMu.Lock()
+		delete(s.transfers, name)
+		s.transfersMu.Unlock()
+	}
+}
+
+// Stats returns a snapshot of every transfer currently in flight.
+func (s *Sower) Stats() (stats []TransferStats) {
+	s.transfersMu.Lock()
+	defer s.transfersMu.Unlock()
+
+	for _, t := range s.transfers {
+		written, rate, eta := monitorETA(t.monitor, t.total)
+
+		stats = append(stats, TransferStats{
+			Name:    t.name,
+			Written: written,
+			Total:   t.total,
+			RateBps: rate,
+			ETA:     eta,
+		})
+	}
+
+	return stats
+}
+
+// monitorETA reads monitor's cumulative bytes and EMA rate and estimates the
+// remaining time to reach total at that rate, or zero if the rate is 0 or
+// total's already been reached.
+func monitorETA(monitor *flow.Monitor, total uint64) (written uint64, rate float64, eta time.Duration) {
+	written = monitor.Total()
+	rate = monitor.Rate()
+
+	if rate > 0 && written < total {
+		eta = time.Duration(float64(total-written) / rate * float64(time.Second))
+	}
+
+	return written, rate, eta
+}
+
+// averageRate returns written/d in bytes/sec, or 0 if d is non-positive, for
+// annotating the "Moved"/"Downloaded"/"Uploaded" completion log lines with a
+// throughput figure.
+func averageRate(written int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+
+	return float64(written) / d.Seconds()
+}
+
+// Progress returns the Writer that Vertex updates for every move, upload
+// and download are published to.
+func (s *Sower) Progress() *progress.Writer {
+	return s.progress
+}
+
+// publishProgress publishes v if s has a progress.Writer attached. Sower
+// values constructed directly (as in tests) have a nil Writer, so this is a
+// no-op rather than a panic.
+func (s *Sower) publishProgress(v progress.Vertex) {
+	if s.progress == nil {
+		return
+	}
+
+	s.progress.Write(v)
+}
+
+// publishPeriodicProgress republishes name's progress as StatusTransferring
+// every periodicProgressInterval, using monitor's live Rate/Total, so a long
+// transfer reports continuous throughput on top of the discrete
+// queued/transferring/verifying/done/error transitions published elsewhere.
+// It returns a func the caller must invoke exactly once, when the transfer
+// finishes, to stop the ticker.
+func (s *Sower) publishPeriodicProgress(name string, total uint64, monitor *flow.Monitor) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(periodicProgressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				written, rate, eta := monitorETA(monitor, total)
+
+				s.publishProgress(progress.Vertex{
+					Name:    name,
+					Status:  progress.StatusTransferring,
+					Total:   total,
+					Written: written,
+					RateBps: rate,
+					ETA:     eta,
+				})
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
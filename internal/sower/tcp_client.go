@@ -23,114 +23,389 @@ package sower
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"net"
+	"time"
 
 	"github.com/tcpaddock/shiplot/internal/config"
+	"github.com/tcpaddock/shiplot/internal/progress"
+	"github.com/tcpaddock/shiplot/internal/transport/noise"
 	"github.com/tcpaddock/shiplot/internal/util"
+	"github.com/tcpaddock/shiplot/internal/util/flow"
+	"github.com/tcpaddock/shiplot/internal/wire"
+	"github.com/zeebo/blake3"
+	"golang.org/x/crypto/blake2b"
 )
 
 type TcpClient struct {
-	cfg config.Config
+	cfg      config.Config
+	limiters *peerLimiters
+	progress *progress.Writer
 }
 
 func NewTcpClient(cfg config.Config) (c *TcpClient) {
 	c = new(TcpClient)
 
 	c.cfg = cfg
+	if cfg.Security.RateLimit.MBPerSec > 0 {
+		c.limiters = newPeerLimiters(cfg.Security.RateLimit.MBPerSec, cfg.Security.RateLimit.BurstMB)
+	}
 
 	return c
 }
 
-func (c *TcpClient) WritePlot(ctx context.Context, name string, size uint64, reader io.Reader) (written int64, err error) {
-	conn, err := c.connect()
+// publishProgress publishes v if c has a progress.Writer attached. A
+// TcpClient constructed directly (as in tests) has a nil Writer, so this is
+// a no-op rather than a panic.
+func (c *TcpClient) publishProgress(v progress.Vertex) {
+	if c.progress == nil {
+		return
+	}
+
+	c.progress.Write(v)
+}
+
+// publishPeriodicProgress republishes name's progress as StatusTransferring
+// every periodicProgressInterval, using monitor's live Rate/Total, mirroring
+// Sower.publishPeriodicProgress on the upload side. It returns a func the
+// caller must invoke exactly once, when the upload finishes, to stop the
+// ticker.
+func (c *TcpClient) publishPeriodicProgress(name string, total uint64, monitor *flow.Monitor) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(periodicProgressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				written := monitor.Total()
+				rate := monitor.Rate()
+
+				var eta time.Duration
+				if rate > 0 && written < total {
+					eta = time.Duration(float64(total-written) / rate * float64(time.Second))
+				}
+
+				c.publishProgress(progress.Vertex{
+					Name:    name,
+					Status:  progress.StatusTransferring,
+					Total:   total,
+					Written: written,
+					RateBps: rate,
+					ETA:     eta,
+				})
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// WritePlot sends name to the configured server. When the server and config
+// both support the framed protocol (the default), the plot's blake3 digest
+// is offered up front so a dropped connection can resume mid-file on retry;
+// cfg.Client.LegacyProtocol forces the old bare length-prefixed protocol for
+// compatibility with servers that haven't upgraded yet. monitor throttles and
+// records the bytes read from reader, including cfg.MaxWriteBytesPerSec if
+// the caller folded it in; pass flow.NewMonitor() for no limit. Progress
+// through queued/transferring/verifying/done/error is published to c's
+// progress.Writer, if any.
+func (c *TcpClient) WritePlot(ctx context.Context, name string, size uint64, reader io.ReadSeeker, monitor *flow.Monitor) (written int64, err error) {
+	c.publishProgress(progress.Vertex{Name: name, Status: progress.StatusTransferring, Total: size})
+	defer func() {
+		if err != nil {
+			c.publishProgress(progress.Vertex{Name: name, Status: progress.StatusError, Total: size, Written: uint64(written), Error: err.Error()})
+			return
+		}
+
+		c.publishProgress(progress.Vertex{Name: name, Status: progress.StatusDone, Total: size, Written: uint64(written)})
+	}()
+
+	conn, err := c.connect(ctx)
 	if err != nil {
 		return 0, err
 	}
 
 	defer conn.Close()
 
-	_, err = c.writeFileName(ctx, name, conn)
+	if c.cfg.Client.LegacyProtocol {
+		written, err = c.legacyWritePlot(ctx, name, size, reader, conn, monitor)
+		return written, err
+	}
+
+	digest, err := hashReadSeeker(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = writeFrame(ctx, conn, msgHello, encodeHello(helloPayload{version: protocolVersion, features: featureResume})); err != nil {
+		return 0, err
+	}
+
+	h, payload, err := readFrame(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if h.msgType != msgHelloAck {
+		return 0, fmt.Errorf("unexpected message type %d during handshake", h.msgType)
+	}
+	ack, err := decodeHelloAck(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	offer := plotOfferPayload{name: name, size: size, digest: digest}
+	if err = writeFrame(ctx, conn, msgPlotOffer, encodePlotOffer(offer)); err != nil {
+		return 0, err
+	}
+
+	h, payload, err = readFrame(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if h.msgType == msgError {
+		e, _ := decodeError(payload)
+		return 0, fmt.Errorf("server rejected plot offer: %s", e.message)
+	}
+	if h.msgType != msgPlotOfferAck {
+		return 0, fmt.Errorf("unexpected message type %d after PLOT_OFFER", h.msgType)
+	}
+	offerAck, err := decodePlotOfferAck(payload)
 	if err != nil {
 		return 0, err
 	}
+	if !offerAck.accept {
+		return 0, fmt.Errorf("server rejected plot offer: %s", offerAck.reason)
+	}
 
-	_, err = c.writeFileSize(ctx, size, conn)
+	offset := offerAck.offset
+	if offset > 0 && ack.features&featureResume != 0 {
+		if _, err = reader.Seek(int64(offset), io.SeekStart); err != nil {
+			return 0, err
+		}
+	} else {
+		offset = 0
+	}
+
+	stopPeriodic := c.publishPeriodicProgress(name, size, monitor)
+	written, err = c.writeData(ctx, flow.NewReader(reader, monitor), conn, int64(offset))
+	stopPeriodic()
 	if err != nil {
 		return 0, err
 	}
 
-	written, err = c.writePlot(ctx, reader, conn)
+	c.publishProgress(progress.Vertex{Name: name, Status: progress.StatusVerifying, Total: size, Written: uint64(written)})
+
+	if err = writeFrame(ctx, conn, msgPlotDone, encodePlotDone(plotDonePayload{digest: digest})); err != nil {
+		return 0, err
+	}
+
+	h, payload, err = readFrame(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if h.msgType == msgError {
+		e, _ := decodeError(payload)
+		return 0, fmt.Errorf("server reported failure: %s", e.message)
+	}
+	if h.msgType != msgPlotDone {
+		return 0, fmt.Errorf("unexpected message type %d after PLOT_DONE", h.msgType)
+	}
+	done, err := decodePlotDone(payload)
 	if err != nil {
 		return 0, err
 	}
+	if done.digest != digest {
+		return 0, fmt.Errorf("server reported mismatched digest for %s", name)
+	}
+
+	return written, nil
+}
 
-	ok := c.readResult(ctx, conn)
-	if !ok {
-		return 0, fmt.Errorf("server returned failure")
+func (c *TcpClient) writeData(ctx context.Context, reader io.Reader, writer io.Writer, alreadySent int64) (written int64, err error) {
+	written = alreadySent
+
+	buf := make([]byte, dataChunkSize)
+	for {
+		n, readErr := io.ReadFull(reader, buf)
+		if n > 0 {
+			chunk := buf[:n]
+			payload := encodeData(dataPayload{data: chunk, crc: crc32.Checksum(chunk, crc32cTable)})
+			if err = writeFrame(ctx, writer, msgData, payload); err != nil {
+				return written, err
+			}
+			written += int64(n)
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return written, readErr
+		}
 	}
 
 	return written, nil
 }
 
-func (c *TcpClient) connect() (conn *net.TCPConn, err error) {
-	tcpAddr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", c.cfg.Client.ServerIp, c.cfg.Client.ServerPort))
+func hashReadSeeker(reader io.ReadSeeker) (digest [digestSize]byte, err error) {
+	h := blake3.New()
+	if _, err = io.Copy(h, reader); err != nil {
+		return digest, err
+	}
+	if _, err = reader.Seek(0, io.SeekStart); err != nil {
+		return digest, err
+	}
+
+	copy(digest[:], h.Sum(nil))
+	return digest, nil
+}
+
+// connect dials the configured server, optionally over mTLS, then performs
+// a Noise static-key handshake if configured, completes the shared-token
+// HMAC challenge, and wraps the connection in the per-peer bandwidth
+// limiter before handing it back.
+func (c *TcpClient) connect(ctx context.Context) (conn net.Conn, err error) {
+	addr := fmt.Sprintf("%s:%d", c.cfg.Client.ServerIp, c.cfg.Client.ServerPort)
+
+	tlsCfg, err := clientTLSConfig(c.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsCfg != nil {
+		conn, err = tls.Dial("tcp", addr, tlsCfg)
+	} else {
+		conn, err = net.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	identity, serverKey, ok, err := clientNoiseIdentity(c.cfg)
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
+	if ok {
+		nconn, err := noise.Dial(conn, identity, serverKey)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = nconn
+	}
 
-	conn, err = net.DialTCP("tcp", nil, tcpAddr)
+	token, err := loadSharedToken(c.cfg)
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
+	if err = clientRespond(conn, token); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if c.limiters != nil {
+		conn = newRateLimitedConn(ctx, conn, c.limiters.forAddr(conn.RemoteAddr()))
+	}
 
 	return conn, nil
 }
 
-func (c *TcpClient) writeFileName(ctx context.Context, name string, writer io.Writer) (written int, err error) {
-	cw := util.NewContextWriter(ctx, writer)
+// legacyWritePlot speaks the original bare protocol over internal/wire: a
+// Header negotiating the protocol version, then FILENAME, SIZE, chunked
+// PAYLOAD and a trailing 32-byte BLAKE2b-256 TRAILER frame, kept for one
+// release behind cfg.Client.LegacyProtocol so servers can be upgraded
+// independently of clients. The trailing digest lets the server detect
+// silent corruption across the staging/network/destination path that a
+// plain byte-count check would miss.
+func (c *TcpClient) legacyWritePlot(ctx context.Context, name string, size uint64, reader io.Reader, conn net.Conn, monitor *flow.Monitor) (written int64, err error) {
+	if err = wire.WriteHeader(ctx, conn, wire.Header{Version: wire.Version}); err != nil {
+		return 0, err
+	}
+
+	if err = wire.WriteFrame(ctx, conn, wire.FrameFileName, []byte(name)); err != nil {
+		return 0, err
+	}
 
-	fileNameSizeByte := byte(len(name))
-	fileNameBytes := []byte(name)
-	w1, err := cw.Write([]byte{fileNameSizeByte})
-	if err != nil {
+	sizeBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(sizeBytes, size)
+	if err = wire.WriteFrame(ctx, conn, wire.FrameSize, sizeBytes); err != nil {
 		return 0, err
 	}
 
-	w2, err := cw.Write(fileNameBytes)
+	stopPeriodic := c.publishPeriodicProgress(name, size, monitor)
+	written, digest, err := c.writePlot(ctx, reader, conn, monitor)
+	stopPeriodic()
 	if err != nil {
 		return 0, err
 	}
 
-	return w1 + w2, nil
-}
+	if err = wire.WriteFrame(ctx, conn, wire.FrameTrailer, digest[:]); err != nil {
+		return 0, err
+	}
 
-func (c *TcpClient) writeFileSize(ctx context.Context, size uint64, writer io.Writer) (written int, err error) {
-	cw := util.NewContextWriter(ctx, writer)
+	c.publishProgress(progress.Vertex{Name: name, Status: progress.StatusVerifying, Total: size, Written: uint64(written)})
 
-	fileSizeBytes := make([]byte, 8)
-	binary.LittleEndian.PutUint64(fileSizeBytes, size)
-	written, err = cw.Write(fileSizeBytes)
+	frameType, value, err := wire.ReadFrame(ctx, conn)
 	if err != nil {
 		return 0, err
 	}
-
-	return
+	if frameType != wire.FrameError {
+		return 0, fmt.Errorf("unexpected frame type %d from server", frameType)
+	}
+	status, message, err := wire.DecodeError(value)
+	if err != nil {
+		return 0, err
+	}
+	switch status {
+	case wire.StatusOK:
+		return written, nil
+	case wire.StatusDigestMismatch:
+		return 0, fmt.Errorf("server reported digest mismatch for %s", name)
+	default:
+		return 0, fmt.Errorf("server returned failure: %s", message)
+	}
 }
 
-func (c *TcpClient) writePlot(ctx context.Context, reader io.Reader, writer io.Writer) (written int64, err error) {
-	cr := util.NewContextReader(ctx, reader)
-	cw := util.NewContextWriter(ctx, writer)
+// writePlot copies reader to writer as chunked PAYLOAD frames through
+// monitor, hashing the plaintext with BLAKE2b-256 along the way so
+// legacyWritePlot can append it as a TRAILER frame for the server to
+// verify.
+func (c *TcpClient) writePlot(ctx context.Context, reader io.Reader, writer io.Writer, monitor *flow.Monitor) (written int64, digest [blake2bDigestSize]byte, err error) {
+	cr := flow.NewReader(util.NewContextReader(ctx, reader), monitor)
 
-	return io.Copy(cw, cr)
-}
+	h, err := blake2b.New256(nil)
+	if err != nil {
+		return 0, digest, err
+	}
+
+	buf := make([]byte, dataChunkSize)
+	for {
+		n, readErr := io.ReadFull(io.TeeReader(cr, h), buf)
+		if n > 0 {
+			if err = wire.WriteFrame(ctx, writer, wire.FramePayload, buf[:n]); err != nil {
+				return written, digest, err
+			}
+			written += int64(n)
+		}
 
-func (c *TcpClient) readResult(ctx context.Context, reader io.Reader) (ok bool) {
-	cr := util.NewContextReader(ctx, reader)
-	result := make([]byte, 1)
-	cr.Read(result)
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return written, digest, readErr
+		}
+	}
 
-	return result[0] == 1
+	copy(digest[:], h.Sum(nil))
+	return written, digest, nil
 }
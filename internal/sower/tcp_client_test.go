@@ -28,6 +28,9 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"github.com/tcpaddock/shiplot/internal/config"
+	"github.com/tcpaddock/shiplot/internal/util/flow"
+	"github.com/tcpaddock/shiplot/internal/wire"
+	"golang.org/x/crypto/blake2b"
 )
 
 func TestNewTcpClient(t *testing.T) {
@@ -43,48 +46,25 @@ func TestNewTcpClient(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
-func TestWriteFileName(t *testing.T) {
+func TestWritePlot(t *testing.T) {
 	// Arrange
 	client := &TcpClient{
 		cfg: config.Config{},
 	}
+	payload := []byte("hello plot")
 	b := bytes.Buffer{}
-	expected := []byte{0x04, 0x74, 0x65, 0x73, 0x74}
+	expectedDigest := blake2b.Sum256(payload)
 
 	// Act
-	_, _ = client.writeFileName(context.Background(), "test", &b)
+	written, digest, err := client.writePlot(context.Background(), bytes.NewReader(payload), &b, flow.NewMonitor())
 
 	// Assert
-	require.Equal(t, expected, b.Bytes())
-}
-
-func TestWriteFileSize(t *testing.T) {
-	// Arrange
-	client := &TcpClient{
-		cfg: config.Config{},
-	}
-	b := bytes.Buffer{}
-	expected := []byte{0x33, 0x33, 0x33, 0x33, 0x1b, 0x00, 0x00, 0x00}
-
-	// Act
-	_, _ = client.writeFileSize(context.Background(), 116823110451, &b)
-
-	// Assert
-	require.Equal(t, expected, b.Bytes())
-}
-
-func TestReadResult(t *testing.T) {
-	// Arrange
-	client := &TcpClient{
-		cfg: config.Config{},
-	}
-	b := bytes.Buffer{}
-	b.Write([]byte{1})
-	expected := true
-
-	// Act
-	actual := client.readResult(context.Background(), &b)
-
-	// Assert
-	require.Equal(t, expected, actual)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(payload)), written)
+	require.Equal(t, expectedDigest, digest)
+
+	frameType, value, err := wire.ReadFrame(context.Background(), &b)
+	require.NoError(t, err)
+	require.Equal(t, wire.FramePayload, frameType)
+	require.Equal(t, payload, value)
 }
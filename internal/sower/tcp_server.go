@@ -23,20 +23,26 @@ package sower
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/binary"
 	"fmt"
-	"io"
 	"net"
 	"strings"
 
 	"github.com/tcpaddock/shiplot/internal/config"
-	"github.com/tcpaddock/shiplot/internal/util"
+	"github.com/tcpaddock/shiplot/internal/transport/noise"
+	"github.com/tcpaddock/shiplot/internal/wire"
 	"golang.org/x/exp/slog"
 )
 
 type TcpServer struct {
-	cfg   config.Config
-	sower *Sower
+	cfg      config.Config
+	sower    *Sower
+	limiters *peerLimiters
+
+	noiseEnabled  bool
+	noiseIdentity noise.Keypair
+	noiseAuthKeys [][]byte
 }
 
 func NewTcpServer(cfg config.Config, sower *Sower) (s *TcpServer) {
@@ -44,6 +50,9 @@ func NewTcpServer(cfg config.Config, sower *Sower) (s *TcpServer) {
 
 	s.cfg = cfg
 	s.sower = sower
+	if cfg.Security.RateLimit.MBPerSec > 0 {
+		s.limiters = newPeerLimiters(cfg.Security.RateLimit.MBPerSec, cfg.Security.RateLimit.BurstMB)
+	}
 
 	return s
 }
@@ -51,16 +60,35 @@ func NewTcpServer(cfg config.Config, sower *Sower) (s *TcpServer) {
 func (s *TcpServer) Run(ctx context.Context) (err error) {
 	endpoint := fmt.Sprintf("%s:%d", s.cfg.Server.Ip, s.cfg.Server.Port)
 	slog.Default().Info(fmt.Sprintf("Starting TCP server on %s", endpoint))
+
 	listener, err := net.Listen("tcp", endpoint)
 	if err != nil {
 		return err
 	}
 
+	tlsCfg, err := serverTLSConfig(s.cfg)
+	if err != nil {
+		return err
+	}
+	if tlsCfg != nil {
+		listener = tls.NewListener(listener, tlsCfg)
+	}
+
+	s.noiseIdentity, s.noiseAuthKeys, s.noiseEnabled, err = serverNoiseIdentity(s.cfg)
+	if err != nil {
+		return err
+	}
+
 	defer listener.Close()
 
 	s.sower.wg.Add(1)
 	go s.runLoop(ctx, listener)
 
+	if s.cfg.Server.ResumeEnabled {
+		s.sower.wg.Add(1)
+		go s.sower.runJanitor(ctx, s.cfg.Server.ResumeTTL)
+	}
+
 	for {
 		select {
 		case <-make(chan struct{}):
@@ -84,68 +112,190 @@ func (s *TcpServer) runLoop(ctx context.Context, listener net.Listener) {
 	}
 }
 
+// handleRequest authenticates the peer (mTLS client certificate CN, a Noise
+// static-key handshake, then a shared-token HMAC challenge) and wraps the
+// connection in the per-peer bandwidth limiter before speaking the framed
+// protocol (see protocol.go): HELLO/HELLO_ACK negotiate version and
+// features, PLOT_OFFER/PLOT_OFFER_ACK negotiate the plot and, if resuming,
+// the byte offset to continue from, and the DATA/PLOT_DONE exchange is
+// handled downstream by Sower.enqueuePlotDownload. cfg.Server.LegacyProtocol
+// falls back to the original bare protocol for one release while clients
+// upgrade.
 func (s *TcpServer) handleRequest(ctx context.Context, conn net.Conn) {
-	fileName, err := s.readFileName(ctx, conn)
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			slog.Default().Error("TLS handshake failed", err)
+			return
+		}
+
+		state := tlsConn.ConnectionState()
+		if len(state.PeerCertificates) > 0 && !allowedCN(s.cfg, state.PeerCertificates[0].Subject.CommonName) {
+			slog.Default().Error("Rejected peer", fmt.Errorf("CN %q is not in the allow-list", state.PeerCertificates[0].Subject.CommonName))
+			return
+		}
+	}
+
+	if s.noiseEnabled {
+		nconn, err := noise.Accept(conn, s.noiseIdentity, s.noiseAuthKeys)
+		if err != nil {
+			slog.Default().Error("Rejected peer during Noise handshake", err)
+			return
+		}
+		conn = nconn
+	}
+
+	token, err := loadSharedToken(s.cfg)
 	if err != nil {
-		slog.Default().Error("Failed to read file name from request", err)
-		_, _ = writeFail(ctx, conn)
+		slog.Default().Error("Failed to load shared token", err)
+		return
+	}
+	if err = serverChallenge(conn, token); err != nil {
+		slog.Default().Error("Rejected peer", err)
 		return
 	}
 
-	fileSize, err := s.readFileSize(ctx, conn)
-	if err != nil {
-		slog.Default().Error("Failed to read file size from request", err)
-		_, _ = writeFail(ctx, conn)
+	if s.limiters != nil {
+		conn = newRateLimitedConn(ctx, conn, s.limiters.forAddr(conn.RemoteAddr()))
+	}
+
+	if s.cfg.Server.LegacyProtocol {
+		s.legacyHandleRequest(ctx, conn)
 		return
 	}
 
-	err = s.sower.enqueuePlotDownload(ctx, fileName, fileSize, conn, conn)
+	h, payload, err := readFrame(ctx, conn)
 	if err != nil {
-		slog.Default().Error("Failed to add plot download to queue", err, slog.String("name", fileName))
-		_, _ = writeFail(ctx, conn)
+		slog.Default().Error("Failed to read HELLO", err)
+		return
+	}
+	if h.msgType != msgHello {
+		slog.Default().Error("Expected HELLO", fmt.Errorf("got message type %d", h.msgType))
+		_ = s.writeError(ctx, conn, errBadRequest, "expected HELLO")
+		return
+	}
+	hello, err := decodeHello(payload)
+	if err != nil {
+		slog.Default().Error("Failed to decode HELLO", err)
+		_ = s.writeError(ctx, conn, errBadRequest, err.Error())
+		return
+	}
+
+	// Mask out featureResume entirely when cfg.Server.ResumeEnabled is false,
+	// regardless of what the client advertised, so framedDownloadPlot always
+	// restarts from zero instead of trusting a stale ".tmp"/".journal" pair.
+	features := hello.features & (featureResume | featureCompression)
+	if !s.cfg.Server.ResumeEnabled {
+		features &^= featureResume
+	}
+	if err = writeFrame(ctx, conn, msgHelloAck, encodeHelloAck(helloAckPayload{version: protocolVersion, features: features})); err != nil {
+		slog.Default().Error("Failed to send HELLO_ACK", err)
 		return
 	}
 
-	_, err = writeSuccess(ctx, conn)
+	h, payload, err = readFrame(ctx, conn)
 	if err != nil {
-		slog.Default().Error("Failed to send success status", err)
+		slog.Default().Error("Failed to read PLOT_OFFER", err)
 		return
 	}
+	if h.msgType != msgPlotOffer {
+		slog.Default().Error("Expected PLOT_OFFER", fmt.Errorf("got message type %d", h.msgType))
+		_ = s.writeError(ctx, conn, errBadRequest, "expected PLOT_OFFER")
+		return
+	}
+	offer, err := decodePlotOffer(payload)
+	if err != nil {
+		slog.Default().Error("Failed to decode PLOT_OFFER", err)
+		_ = s.writeError(ctx, conn, errBadRequest, err.Error())
+		return
+	}
+
+	if !strings.HasSuffix(offer.name, ".plot") {
+		reason := fmt.Sprintf("rejected plot name %q", offer.name)
+		slog.Default().Error("Rejected PLOT_OFFER", fmt.Errorf("%s", reason))
+		_ = writeFrame(ctx, conn, msgPlotOfferAck, encodePlotOfferAck(plotOfferAckPayload{accept: false, reason: reason}))
+		return
+	}
+
+	err = s.sower.enqueuePlotDownload(ctx, offer.name, offer.size, offer.digest, features&featureResume != 0, conn, conn)
+	if err != nil {
+		slog.Default().Error("Failed to add plot download to queue", err, slog.String("name", offer.name))
+		_ = writeFrame(ctx, conn, msgPlotOfferAck, encodePlotOfferAck(plotOfferAckPayload{accept: false, reason: err.Error()}))
+	}
 }
 
-func (s *TcpServer) readFileName(ctx context.Context, conn net.Conn) (name string, err error) {
-	cr := util.NewContextReader(ctx, conn)
+func (s *TcpServer) writeError(ctx context.Context, conn net.Conn, code errorCode, message string) error {
+	return writeFrame(ctx, conn, msgError, encodeError(errorPayload{code: code, message: message}))
+}
 
-	fileNameSizeBytes := make([]byte, 1)
-	_, err = io.ReadFull(cr, fileNameSizeBytes)
+// legacyHandleRequest speaks the original bare protocol over internal/wire:
+// a Header negotiating the protocol version, then FILENAME and SIZE frames,
+// kept for one release behind cfg.Server.LegacyProtocol so clients can be
+// upgraded independently of the server. The final status is sent by
+// Sower.legacyDownloadPlot once the transfer actually finishes, since
+// enqueuePlotDownload only reports whether the work was queued.
+func (s *TcpServer) legacyHandleRequest(ctx context.Context, conn net.Conn) {
+	h, err := wire.ReadHeader(ctx, conn)
 	if err != nil {
-		return "", err
+		slog.Default().Error("Failed to read wire header from request", err)
+		_ = wire.WriteError(ctx, conn, wire.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err = wire.Negotiate(h.Version); err != nil {
+		slog.Default().Error("Rejected request", err)
+		_ = wire.WriteError(ctx, conn, wire.StatusUnsupportedVersion, err.Error())
+		return
 	}
 
-	fileNameBytes := make([]byte, int(fileNameSizeBytes[0]))
-	_, err = io.ReadFull(cr, fileNameBytes)
+	fileName, err := s.readFileName(ctx, conn)
 	if err != nil {
-		return "", err
+		slog.Default().Error("Failed to read file name from request", err)
+		_ = wire.WriteError(ctx, conn, wire.StatusFail, err.Error())
+		return
 	}
 
-	fileName := string(fileNameBytes)
-	if !strings.HasSuffix(fileName, ".plot") {
-		return "", fmt.Errorf("request provided incorrect file name %s", fileName)
+	fileSize, err := s.readFileSize(ctx, conn)
+	if err != nil {
+		slog.Default().Error("Failed to read file size from request", err)
+		_ = wire.WriteError(ctx, conn, wire.StatusFail, err.Error())
+		return
 	}
 
-	return fileName, nil
+	err = s.sower.enqueuePlotDownload(ctx, fileName, fileSize, [digestSize]byte{}, false, conn, conn)
+	if err != nil {
+		slog.Default().Error("Failed to add plot download to queue", err, slog.String("name", fileName))
+		_ = wire.WriteError(ctx, conn, wire.StatusFail, err.Error())
+		return
+	}
 }
 
-func (s *TcpServer) readFileSize(ctx context.Context, reader io.Reader) (size uint64, err error) {
-	cr := util.NewContextReader(ctx, reader)
+func (s *TcpServer) readFileName(ctx context.Context, conn net.Conn) (name string, err error) {
+	frameType, value, err := wire.ReadFrame(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+	if frameType != wire.FrameFileName {
+		return "", fmt.Errorf("expected FILENAME frame, got type %d", frameType)
+	}
+
+	name = string(value)
+	if !strings.HasSuffix(name, ".plot") {
+		return "", fmt.Errorf("request provided incorrect file name %s", name)
+	}
+
+	return name, nil
+}
 
-	fileSizeBytes := make([]byte, 8)
-	_, err = io.ReadFull(cr, fileSizeBytes)
+func (s *TcpServer) readFileSize(ctx context.Context, conn net.Conn) (size uint64, err error) {
+	frameType, value, err := wire.ReadFrame(ctx, conn)
 	if err != nil {
 		return 0, err
 	}
+	if frameType != wire.FrameSize {
+		return 0, fmt.Errorf("expected SIZE frame, got type %d", frameType)
+	}
+	if len(value) != 8 {
+		return 0, fmt.Errorf("malformed SIZE frame")
+	}
 
-	fileSize := binary.LittleEndian.Uint64(fileSizeBytes)
-
-	return fileSize, nil
+	return binary.LittleEndian.Uint64(value), nil
 }
@@ -22,12 +22,14 @@ THE SOFTWARE.
 package sower
 
 import (
-	"bytes"
 	"context"
+	"encoding/binary"
+	"net"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"github.com/tcpaddock/shiplot/internal/config"
+	"github.com/tcpaddock/shiplot/internal/wire"
 )
 
 func TestNewTcpServer(t *testing.T) {
@@ -50,14 +52,20 @@ func TestReadFileName(t *testing.T) {
 		cfg:   config.Config{},
 		sower: &Sower{},
 	}
-	b := bytes.Buffer{}
-	_, _ = b.Write([]byte{0x09, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x70, 0x6c, 0x6f, 0x74})
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
 	expected := "test.plot"
+	go func() {
+		_ = wire.WriteFrame(context.Background(), clientConn, wire.FrameFileName, []byte(expected))
+	}()
 
 	// Act
-	actual, _ := server.readFileName(context.Background(), &b)
+	actual, err := server.readFileName(context.Background(), serverConn)
 
 	// Assert
+	require.NoError(t, err)
 	require.Equal(t, expected, actual)
 }
 
@@ -67,13 +75,21 @@ func TestReadFileSize(t *testing.T) {
 		cfg:   config.Config{},
 		sower: &Sower{},
 	}
-	b := bytes.Buffer{}
-	_, _ = b.Write([]byte{0x33, 0x33, 0x33, 0x33, 0x1b, 0x00, 0x00, 0x00})
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
 	expected := uint64(116823110451)
+	go func() {
+		value := make([]byte, 8)
+		binary.LittleEndian.PutUint64(value, expected)
+		_ = wire.WriteFrame(context.Background(), clientConn, wire.FrameSize, value)
+	}()
 
 	// Act
-	actual, _ := server.readFileSize(context.Background(), &b)
+	actual, err := server.readFileSize(context.Background(), serverConn)
 
 	// Assert
+	require.NoError(t, err)
 	require.Equal(t, expected, actual)
 }
@@ -0,0 +1,237 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package noise
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/flynn/noise"
+)
+
+// maxFrameSize bounds a single transport-phase ciphertext frame, well under
+// ChaChaPoly's 2^36-byte limit and comfortably larger than any handshake or
+// protocol.go DATA-frame payload it will ever carry.
+const maxFrameSize = 1 << 20
+
+// Conn wraps a net.Conn that has completed a Noise_XK_25519_ChaChaPoly_BLAKE2b
+// handshake, encrypting Write calls and decrypting Read calls with the
+// resulting transport cipher states. The remote peer's verified static
+// public key is available via RemoteStatic.
+type Conn struct {
+	net.Conn
+	send         *noise.CipherState
+	recv         *noise.CipherState
+	remoteStatic []byte
+	readBuf      []byte
+}
+
+// RemoteStatic returns the remote peer's static public key, verified during
+// the handshake.
+func (c *Conn) RemoteStatic() []byte {
+	return c.remoteStatic
+}
+
+// Read decrypts and returns previously-buffered plaintext before reading and
+// decrypting the next frame from the underlying conn.
+func (c *Conn) Read(p []byte) (n int, err error) {
+	if len(c.readBuf) == 0 {
+		frame, err := readFrame(c.Conn)
+		if err != nil {
+			return 0, err
+		}
+
+		plaintext, err := c.recv.Decrypt(nil, nil, frame)
+		if err != nil {
+			return 0, fmt.Errorf("noise: failed to decrypt frame: %w", err)
+		}
+
+		c.readBuf = plaintext
+	}
+
+	n = copy(p, c.readBuf)
+	c.readBuf = c.readBuf[n:]
+
+	return n, nil
+}
+
+// Write encrypts p as a single frame and writes it to the underlying conn,
+// chunking it first if it's larger than maxFrameSize allows once the
+// ChaChaPoly tag is accounted for.
+func (c *Conn) Write(p []byte) (n int, err error) {
+	const maxPlaintext = maxFrameSize - 16 // 16-byte ChaChaPoly tag
+
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxPlaintext {
+			chunk = chunk[:maxPlaintext]
+		}
+
+		frame, err := c.send.Encrypt(nil, nil, chunk)
+		if err != nil {
+			return n, fmt.Errorf("noise: failed to encrypt frame: %w", err)
+		}
+		if err = writeFrame(c.Conn, frame); err != nil {
+			return n, err
+		}
+
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+
+	return n, nil
+}
+
+func readFrame(r io.Reader) (frame []byte, err error) {
+	var lenBuf [4]byte
+	if _, err = io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.LittleEndian.Uint32(lenBuf[:])
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("noise: frame of %d bytes exceeds maximum of %d", size, maxFrameSize)
+	}
+
+	frame = make([]byte, size)
+	if _, err = io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+
+	return frame, nil
+}
+
+func writeFrame(w io.Writer, frame []byte) (err error) {
+	var lenBuf [4]byte
+	binary.LittleEndian.PutUint32(lenBuf[:], uint32(len(frame)))
+
+	if _, err = w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+
+	_, err = w.Write(frame)
+	return err
+}
+
+// Dial performs the initiator side of the XK handshake over conn: the
+// client's static key is revealed to the server only after it's
+// Diffie-Hellman confirmed the server it's talking to holds serverKey's
+// private key. The caller is responsible for establishing conn (e.g.
+// net.Dial) and closing it.
+func Dial(conn net.Conn, identity Keypair, serverKey []byte) (nc *Conn, err error) {
+	hs, err := noise.NewHandshakeState(noise.Config{
+		CipherSuite:   cipherSuite,
+		Pattern:       noise.HandshakeXK,
+		Initiator:     true,
+		StaticKeypair: noise.DHKey{Private: identity.Private, Public: identity.Public},
+		PeerStatic:    serverKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// -> e, es
+	msg, _, _, err := hs.WriteMessage(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = writeFrame(conn, msg); err != nil {
+		return nil, err
+	}
+
+	// <- e, ee
+	frame, err := readFrame(conn)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, _, err = hs.ReadMessage(nil, frame); err != nil {
+		return nil, fmt.Errorf("noise: handshake message 2 rejected: %w", err)
+	}
+
+	// -> s, se
+	msg, cs1, cs2, err := hs.WriteMessage(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = writeFrame(conn, msg); err != nil {
+		return nil, err
+	}
+
+	// The initiator sends with cs1 and receives with cs2; the responder's
+	// Accept does the opposite, since both sides derive the same pair of
+	// keys in the same order but use them in mirrored directions.
+	return &Conn{Conn: conn, send: cs1, recv: cs2, remoteStatic: serverKey}, nil
+}
+
+// Accept performs the responder side of the XK handshake over conn and
+// verifies the client's revealed static public key against authorized,
+// rejecting the connection if it isn't present. An empty authorized list
+// rejects every peer, since an open Noise allow-list defeats the point of
+// authenticating by static key.
+func Accept(conn net.Conn, identity Keypair, authorizedKeys [][]byte) (nc *Conn, err error) {
+	hs, err := noise.NewHandshakeState(noise.Config{
+		CipherSuite:   cipherSuite,
+		Pattern:       noise.HandshakeXK,
+		Initiator:     false,
+		StaticKeypair: noise.DHKey{Private: identity.Private, Public: identity.Public},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// -> e, es
+	frame, err := readFrame(conn)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, _, err = hs.ReadMessage(nil, frame); err != nil {
+		return nil, fmt.Errorf("noise: handshake message 1 rejected: %w", err)
+	}
+
+	// <- e, ee
+	msg, _, _, err := hs.WriteMessage(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = writeFrame(conn, msg); err != nil {
+		return nil, err
+	}
+
+	// -> s, se
+	frame, err = readFrame(conn)
+	if err != nil {
+		return nil, err
+	}
+	_, cs1, cs2, err := hs.ReadMessage(nil, frame)
+	if err != nil {
+		return nil, fmt.Errorf("noise: handshake message 3 rejected: %w", err)
+	}
+
+	remoteStatic := hs.PeerStatic()
+	if len(authorizedKeys) == 0 || !authorized(authorizedKeys, remoteStatic) {
+		return nil, fmt.Errorf("noise: peer static key %x is not in the authorized-keys list", remoteStatic)
+	}
+
+	return &Conn{Conn: conn, send: cs2, recv: cs1, remoteStatic: remoteStatic}, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package noise
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialAcceptRoundTrip(t *testing.T) {
+	// Arrange
+	clientKP, err := GenerateKeypair()
+	require.NoError(t, err)
+	serverKP, err := GenerateKeypair()
+	require.NoError(t, err)
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	type result struct {
+		conn *Conn
+		err  error
+	}
+	serverDone := make(chan result, 1)
+	go func() {
+		c, err := Accept(serverConn, serverKP, [][]byte{clientKP.Public})
+		serverDone <- result{c, err}
+	}()
+
+	// Act
+	client, err := Dial(clientConn, clientKP, serverKP.Public)
+	require.NoError(t, err)
+
+	server := <-serverDone
+	require.NoError(t, server.err)
+
+	// Assert: both sides agree on the other's static key.
+	require.Equal(t, serverKP.Public, client.RemoteStatic())
+	require.Equal(t, clientKP.Public, server.conn.RemoteStatic())
+
+	// Assert: data written by one side is readable on the other.
+	go func() {
+		_, _ = client.Write([]byte("hello server"))
+	}()
+	buf := make([]byte, len("hello server"))
+	_, err = io.ReadFull(server.conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello server", string(buf))
+}
+
+func TestAcceptRejectsUnauthorizedKey(t *testing.T) {
+	// Arrange
+	clientKP, err := GenerateKeypair()
+	require.NoError(t, err)
+	serverKP, err := GenerateKeypair()
+	require.NoError(t, err)
+	otherKP, err := GenerateKeypair()
+	require.NoError(t, err)
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	type result struct {
+		conn *Conn
+		err  error
+	}
+	serverDone := make(chan result, 1)
+	go func() {
+		// Only otherKP.Public is authorized, not the connecting client's.
+		c, err := Accept(serverConn, serverKP, [][]byte{otherKP.Public})
+		serverDone <- result{c, err}
+	}()
+
+	// Act: Dial completes its half of the handshake without waiting for a
+	// reply, so it succeeds even though the server is about to reject it.
+	_, _ = Dial(clientConn, clientKP, serverKP.Public)
+
+	// Assert: the server rejects the peer's static key.
+	server := <-serverDone
+	require.Error(t, server.err)
+	require.Nil(t, server.conn)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package noise wraps net.Conn with an authenticated, encrypted transport
+// built on the Noise Protocol Framework (Noise_XK_25519_ChaChaPoly_BLAKE2b).
+// Each peer has a static Curve25519 identity keypair; a server accepts only
+// clients whose static public key appears in its authorized-keys list, and
+// a client connects only to the server whose static public key it already
+// knows, in the same spirit as SSH host keys and authorized_keys.
+package noise
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flynn/noise"
+	"golang.org/x/crypto/curve25519"
+)
+
+// KeySize is the size in bytes of a Curve25519 public or private key.
+const KeySize = 32
+
+var cipherSuite = noise.NewCipherSuite(noise.DH25519, noise.CipherChaChaPoly, noise.HashBLAKE2b)
+
+// Keypair is a peer's static Curve25519 identity used to authenticate the
+// Noise handshake.
+type Keypair struct {
+	Public  []byte
+	Private []byte
+}
+
+// GenerateKeypair creates a new random static identity.
+func GenerateKeypair() (kp Keypair, err error) {
+	dh, err := cipherSuite.GenerateKeypair(rand.Reader)
+	if err != nil {
+		return Keypair{}, err
+	}
+
+	return Keypair{Public: dh.Public, Private: dh.Private}, nil
+}
+
+// SaveKeypair writes kp's private key to path (hex-encoded, 0600) and its
+// public key to path+".pub" (hex-encoded, 0644), mirroring the ssh-keygen
+// private/public file pair so the public key can be handed out for an
+// authorized-keys list without exposing the private key.
+func SaveKeypair(kp Keypair, path string) (err error) {
+	if err = os.WriteFile(path, []byte(hex.EncodeToString(kp.Private)+"\n"), 0600); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path+".pub", []byte(hex.EncodeToString(kp.Public)+"\n"), 0644)
+}
+
+// LoadKeypair reads a hex-encoded private key from path and derives the
+// matching public key.
+func LoadKeypair(path string) (kp Keypair, err error) {
+	private, err := readHexFile(path)
+	if err != nil {
+		return Keypair{}, err
+	}
+	if len(private) != KeySize {
+		return Keypair{}, fmt.Errorf("noise: %s does not contain a %d-byte private key", path, KeySize)
+	}
+
+	public, err := curve25519.X25519(private, curve25519.Basepoint)
+	if err != nil {
+		return Keypair{}, err
+	}
+
+	return Keypair{Public: public, Private: private}, nil
+}
+
+// LoadPublicKey reads a single hex-encoded public key from path, e.g. a
+// client's client.serverKey file.
+func LoadPublicKey(path string) (public []byte, err error) {
+	public, err = readHexFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(public) != KeySize {
+		return nil, fmt.Errorf("noise: %s does not contain a %d-byte public key", path, KeySize)
+	}
+
+	return public, nil
+}
+
+// LoadAuthorizedKeys reads a server.authorizedKeys file: one hex-encoded
+// public key per line, blank lines and "#"-prefixed comments ignored.
+func LoadAuthorizedKeys(path string) (keys [][]byte, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, err := hex.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("noise: %s: invalid authorized key %q: %w", path, line, err)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("noise: %s: authorized key %q is not %d bytes", path, line, KeySize)
+		}
+
+		keys = append(keys, key)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// authorized reports whether public appears in keys.
+func authorized(keys [][]byte, public []byte) bool {
+	for _, k := range keys {
+		if string(k) == string(public) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func readHexFile(path string) (b []byte, err error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return hex.DecodeString(strings.TrimSpace(string(raw)))
+}
@@ -0,0 +1,72 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package noise
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveLoadKeypair(t *testing.T) {
+	// Arrange
+	kp, err := GenerateKeypair()
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "identity.key")
+
+	// Act
+	require.NoError(t, SaveKeypair(kp, path))
+	loaded, err := LoadKeypair(path)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, kp.Private, loaded.Private)
+	require.Equal(t, kp.Public, loaded.Public)
+
+	pub, err := LoadPublicKey(path + ".pub")
+	require.NoError(t, err)
+	require.Equal(t, kp.Public, pub)
+}
+
+func TestLoadAuthorizedKeys(t *testing.T) {
+	// Arrange
+	a, err := GenerateKeypair()
+	require.NoError(t, err)
+	b, err := GenerateKeypair()
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "authorized_keys")
+	contents := "# comment\n\n" + hex.EncodeToString(a.Public) + "\n" + hex.EncodeToString(b.Public) + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+
+	// Act
+	keys, err := LoadAuthorizedKeys(path)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.True(t, authorized(keys, a.Public))
+	require.True(t, authorized(keys, b.Public))
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package flow provides token-bucket rate limiting and EMA throughput
+// sampling for io.Reader/io.Writer transfers, so multiple plots in flight
+// at once can share a byte/sec budget instead of each saturating the NIC
+// or staging disk on its own. This is an operator-configured, per-transfer
+// budget; it's not a substitute for sower's per-remote-IP peerLimiters,
+// which guards the raw connection against any one peer regardless of what
+// transfer-level budget applies (see the doc comment on peerLimiters).
+package flow
+
+import (
+	"sync"
+	"time"
+)
+
+// minSleep bounds how long Manager.Limit sleeps while waiting for its
+// budget to refill, so it wakes up often enough to notice new capacity
+// without busy-spinning.
+const minSleep = 10 * time.Millisecond
+
+// Manager enforces a shared maxRate, in bytes/sec, across every Monitor
+// that reserves bytes through it. One Manager might be shared by every
+// concurrent transfer on a server, so N plots in flight at once can't
+// collectively exceed the configured budget even though each has its own
+// Monitor for per-transfer stats.
+type Manager struct {
+	mu        sync.Mutex
+	maxRate   uint64
+	available float64
+	last      time.Time
+}
+
+// NewManager creates a Manager enforcing maxBytesPerSec across every
+// Monitor that shares it. A maxBytesPerSec of 0 means unlimited; callers
+// that don't want a limit should just pass a nil *Manager around instead
+// of calling NewManager(0).
+func NewManager(maxBytesPerSec uint64) *Manager {
+	return &Manager{
+		maxRate: maxBytesPerSec,
+		last:    time.Now(),
+	}
+}
+
+// Limit blocks until at least one byte of n is available under maxRate,
+// then reserves and returns however many of the n pending bytes the
+// budget currently allows. A nil Manager, or one created with a zero
+// rate, is unlimited and always returns n.
+func (m *Manager) Limit(n int) int {
+	if m == nil || m.maxRate == 0 || n <= 0 {
+		return n
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for {
+		m.refill()
+		if m.available >= 1 {
+			break
+		}
+
+		m.mu.Unlock()
+		time.Sleep(minSleep)
+		m.mu.Lock()
+	}
+
+	allowed := n
+	if float64(allowed) > m.available {
+		allowed = int(m.available)
+	}
+	m.available -= float64(allowed)
+
+	return allowed
+}
+
+// refill credits the budget for however much time has passed since the
+// last call, capped at one second's worth so an idle Manager can't bank
+// an unbounded burst.
+func (m *Manager) refill() {
+	now := time.Now()
+	elapsed := now.Sub(m.last)
+	m.last = now
+
+	m.available += float64(m.maxRate) * elapsed.Seconds()
+	if cap := float64(m.maxRate); m.available > cap {
+		m.available = cap
+	}
+}
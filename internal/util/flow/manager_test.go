@@ -0,0 +1,74 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package flow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerLimitUnlimited(t *testing.T) {
+	var tests = []struct {
+		name    string
+		manager *Manager
+	}{
+		{name: "nil manager", manager: nil},
+		{name: "zero rate", manager: NewManager(0)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			actual := test.manager.Limit(1024)
+
+			// Assert
+			require.Equal(t, 1024, actual)
+		})
+	}
+}
+
+func TestManagerLimitCapsToAvailableBudget(t *testing.T) {
+	// Arrange
+	m := NewManager(100)
+	m.available = 40
+
+	// Act
+	actual := m.Limit(1000)
+
+	// Assert
+	require.Equal(t, 40, actual)
+	require.InDelta(t, 0, m.available, 1)
+}
+
+func TestManagerLimitBlocksUntilBudgetRefills(t *testing.T) {
+	// Arrange
+	m := NewManager(1000)
+	m.available = 0
+
+	// Act
+	actual := m.Limit(10)
+
+	// Assert: Limit must have waited for refill() to credit at least one
+	// byte rather than returning 0 immediately.
+	require.Greater(t, actual, 0)
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package flow
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// defaultSamplePeriod is how often Monitor recomputes its EMA.
+	defaultSamplePeriod = 100 * time.Millisecond
+	// defaultAlpha weights the newest sample against the running EMA.
+	defaultAlpha = 0.25
+)
+
+// Monitor tracks how many bytes have passed through a single transfer and
+// maintains an exponential moving average of its throughput, resampled
+// every samplePeriod: rEMA = alpha*rSample + (1-alpha)*rEMA. When created
+// with one or more Managers, it also throttles the transfer to their
+// shared budgets before recording each observation.
+type Monitor struct {
+	mu sync.Mutex
+
+	managers []*Manager
+
+	alpha        float64
+	samplePeriod time.Duration
+
+	totalBytes  uint64
+	periodBytes uint64
+	periodStart time.Time
+	rEMA        float64
+}
+
+// NewMonitor creates a Monitor that throttles through managers, in order,
+// before recording each observation. managers may be empty for an
+// unthrottled, stats-only Monitor.
+func NewMonitor(managers ...*Manager) *Monitor {
+	return &Monitor{
+		managers:     managers,
+		alpha:        defaultAlpha,
+		samplePeriod: defaultSamplePeriod,
+		periodStart:  time.Now(),
+	}
+}
+
+// Limit reports how many of n pending bytes the caller may transfer right
+// now, blocking on each configured Manager in turn until its budget
+// allows at least part of n through.
+func (m *Monitor) Limit(n int) int {
+	for _, manager := range m.managers {
+		n = manager.Limit(n)
+	}
+
+	return n
+}
+
+// Observe records n bytes having actually been transferred, recomputing
+// the EMA once samplePeriod has elapsed since the last sample.
+func (m *Monitor) Observe(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.totalBytes += uint64(n)
+	m.periodBytes += uint64(n)
+
+	elapsed := time.Since(m.periodStart)
+	if elapsed < m.samplePeriod {
+		return
+	}
+
+	rSample := float64(m.periodBytes) / elapsed.Seconds()
+	m.rEMA = m.alpha*rSample + (1-m.alpha)*m.rEMA
+	m.periodBytes = 0
+	m.periodStart = time.Now()
+}
+
+// Rate returns the most recently sampled EMA throughput, in bytes/sec.
+func (m *Monitor) Rate() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.rEMA
+}
+
+// Total returns the cumulative number of bytes observed.
+func (m *Monitor) Total() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.totalBytes
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package flow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitorObserveAccumulatesTotal(t *testing.T) {
+	// Arrange
+	m := NewMonitor()
+
+	// Act
+	m.Observe(100)
+	m.Observe(50)
+
+	// Assert
+	require.Equal(t, uint64(150), m.Total())
+}
+
+func TestMonitorObserveSamplesEMAAfterPeriod(t *testing.T) {
+	// Arrange
+	m := NewMonitor()
+	m.samplePeriod = 0
+	m.periodStart = time.Now().Add(-time.Second)
+
+	// Act
+	m.Observe(1000)
+
+	// Assert
+	require.Greater(t, m.Rate(), float64(0))
+}
+
+func TestMonitorObserveDoesNotSampleBeforePeriodElapses(t *testing.T) {
+	// Arrange
+	m := NewMonitor()
+	m.samplePeriod = time.Minute
+	m.periodStart = time.Now()
+
+	// Act
+	m.Observe(1000)
+
+	// Assert
+	require.Equal(t, float64(0), m.Rate())
+}
+
+func TestMonitorLimitChainsManagers(t *testing.T) {
+	// Arrange
+	m := NewMonitor(NewManager(100), NewManager(10))
+
+	// Act
+	actual := m.Limit(1000)
+
+	// Assert: the smaller of the two managers' budgets wins.
+	require.LessOrEqual(t, actual, 10)
+}
+
+func TestMonitorLimitUnthrottled(t *testing.T) {
+	// Arrange
+	m := NewMonitor()
+
+	// Act
+	actual := m.Limit(1000)
+
+	// Assert
+	require.Equal(t, 1000, actual)
+}
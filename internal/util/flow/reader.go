@@ -0,0 +1,46 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package flow
+
+import "io"
+
+// Reader wraps an io.Reader, throttling and recording every Read through a
+// Monitor.
+type Reader struct {
+	r       io.Reader
+	monitor *Monitor
+}
+
+// NewReader wraps r so every Read is throttled and recorded through
+// monitor.
+func NewReader(r io.Reader, monitor *Monitor) *Reader {
+	return &Reader{r: r, monitor: monitor}
+}
+
+func (r *Reader) Read(p []byte) (n int, err error) {
+	allowed := r.monitor.Limit(len(p))
+
+	n, err = r.r.Read(p[:allowed])
+	r.monitor.Observe(n)
+
+	return n, err
+}
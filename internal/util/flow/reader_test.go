@@ -0,0 +1,69 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package flow
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderReadUnthrottled(t *testing.T) {
+	// Arrange
+	r := NewReader(strings.NewReader("hello world"), NewMonitor())
+
+	// Act
+	b, err := io.ReadAll(r)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(b))
+}
+
+func TestReaderReadRecordsObservations(t *testing.T) {
+	// Arrange
+	monitor := NewMonitor()
+	r := NewReader(strings.NewReader("hello world"), monitor)
+
+	// Act
+	_, err := io.ReadAll(r)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, uint64(len("hello world")), monitor.Total())
+}
+
+func TestReaderReadThrottled(t *testing.T) {
+	// Arrange
+	monitor := NewMonitor(NewManager(4))
+	r := NewReader(strings.NewReader("hello world"), monitor)
+	buf := make([]byte, 11)
+
+	// Act
+	n, err := r.Read(buf)
+
+	// Assert
+	require.NoError(t, err)
+	require.LessOrEqual(t, n, 4)
+}
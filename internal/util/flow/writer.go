@@ -0,0 +1,55 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package flow
+
+import "io"
+
+// Writer wraps an io.Writer, throttling and recording every Write through
+// a Monitor.
+type Writer struct {
+	w       io.Writer
+	monitor *Monitor
+}
+
+// NewWriter wraps w so every Write is throttled and recorded through
+// monitor.
+func NewWriter(w io.Writer, monitor *Monitor) *Writer {
+	return &Writer{w: w, monitor: monitor}
+}
+
+// Write satisfies io.Writer, chunking p across as many throttled Writes to
+// the underlying writer as the shared budget requires.
+func (w *Writer) Write(p []byte) (n int, err error) {
+	for n < len(p) {
+		allowed := w.monitor.Limit(len(p) - n)
+
+		written, werr := w.w.Write(p[n : n+allowed])
+		n += written
+		w.monitor.Observe(written)
+
+		if werr != nil {
+			return n, werr
+		}
+	}
+
+	return n, nil
+}
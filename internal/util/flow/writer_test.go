@@ -0,0 +1,60 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package flow
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterWriteUnthrottled(t *testing.T) {
+	// Arrange
+	var b bytes.Buffer
+	w := NewWriter(&b, NewMonitor())
+
+	// Act
+	n, err := w.Write([]byte("hello world"))
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, 11, n)
+	require.Equal(t, "hello world", b.String())
+}
+
+func TestWriterWriteChunksAcrossBudget(t *testing.T) {
+	// Arrange
+	var b bytes.Buffer
+	monitor := NewMonitor(NewManager(4))
+	w := NewWriter(&b, monitor)
+
+	// Act
+	n, err := w.Write([]byte("hello world"))
+
+	// Assert: the whole slice eventually gets written even though the
+	// budget only allows a few bytes through per call.
+	require.NoError(t, err)
+	require.Equal(t, 11, n)
+	require.Equal(t, "hello world", b.String())
+	require.Equal(t, uint64(11), monitor.Total())
+}
@@ -0,0 +1,193 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package wire provides a small length-prefixed, versioned framing format
+// for peers that need more structure than a bare byte stream but don't need
+// the full message-oriented protocol in sower/protocol.go. A wire exchange
+// opens with a fixed Header (magic, version, flags) and continues as a
+// sequence of TLV frames, each a 1-byte FrameType, a uint32 length and that
+// many bytes of value.
+package wire
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/tcpaddock/shiplot/internal/util"
+)
+
+// Magic identifies the start of a wire exchange.
+var Magic = [8]byte{'S', 'H', 'I', 'P', 'L', 'O', 'T', 0x01}
+
+// Version is the highest protocol version this build speaks. Peers
+// negotiate down to whichever version is lower via Negotiate.
+const Version uint16 = 1
+
+// headerSize is len(Magic) + 2 bytes version + 2 bytes flags.
+const headerSize = 8 + 2 + 2
+
+// maxFrameSize bounds a single frame's value so a corrupt or hostile length
+// field can't force an unbounded allocation.
+const maxFrameSize = 1 << 25 // 32MiB
+
+// FrameType identifies the payload carried by a frame.
+type FrameType uint8
+
+const (
+	FrameFileName FrameType = iota + 1
+	FrameSize
+	FramePayload
+	FrameTrailer
+	FrameError
+)
+
+// Status values reported in an Error frame.
+type Status uint16
+
+const (
+	StatusOK Status = iota
+	StatusFail
+	StatusDigestMismatch
+	StatusBadRequest
+	StatusUnsupportedVersion
+)
+
+// Header is the fixed preamble that opens every wire exchange.
+type Header struct {
+	Version uint16
+	Flags   uint16
+}
+
+// WriteHeader writes h's magic, version and flags to writer.
+func WriteHeader(ctx context.Context, writer io.Writer, h Header) (err error) {
+	cw := util.NewContextWriter(ctx, writer)
+
+	buf := make([]byte, headerSize)
+	copy(buf[:8], Magic[:])
+	binary.LittleEndian.PutUint16(buf[8:10], h.Version)
+	binary.LittleEndian.PutUint16(buf[10:12], h.Flags)
+
+	_, err = cw.Write(buf)
+	return err
+}
+
+// ReadHeader reads a Header from reader and validates its magic.
+func ReadHeader(ctx context.Context, reader io.Reader) (h Header, err error) {
+	cr := util.NewContextReader(ctx, reader)
+
+	buf := make([]byte, headerSize)
+	if _, err = io.ReadFull(cr, buf); err != nil {
+		return h, err
+	}
+	if !bytes.Equal(buf[:8], Magic[:]) {
+		return h, fmt.Errorf("wire: bad magic %x", buf[:8])
+	}
+
+	h.Version = binary.LittleEndian.Uint16(buf[8:10])
+	h.Flags = binary.LittleEndian.Uint16(buf[10:12])
+
+	return h, nil
+}
+
+// Negotiate returns the highest version both this build and a peer
+// reporting peerVersion understand, or an error if peerVersion is too old
+// for either side to speak.
+func Negotiate(peerVersion uint16) (version uint16, err error) {
+	if peerVersion == 0 {
+		return 0, fmt.Errorf("wire: peer reported unsupported protocol version 0")
+	}
+	if peerVersion < Version {
+		return peerVersion, nil
+	}
+
+	return Version, nil
+}
+
+// WriteFrame writes a TLV frame: a 1-byte type, a uint32 length, and value.
+func WriteFrame(ctx context.Context, writer io.Writer, frameType FrameType, value []byte) (err error) {
+	cw := util.NewContextWriter(ctx, writer)
+
+	header := make([]byte, 5)
+	header[0] = byte(frameType)
+	binary.LittleEndian.PutUint32(header[1:], uint32(len(value)))
+
+	if _, err = cw.Write(header); err != nil {
+		return err
+	}
+	if len(value) == 0 {
+		return nil
+	}
+
+	_, err = cw.Write(value)
+	return err
+}
+
+// ReadFrame reads a TLV frame's type and value from reader.
+func ReadFrame(ctx context.Context, reader io.Reader) (frameType FrameType, value []byte, err error) {
+	cr := util.NewContextReader(ctx, reader)
+
+	header := make([]byte, 5)
+	if _, err = io.ReadFull(cr, header); err != nil {
+		return 0, nil, err
+	}
+
+	frameType = FrameType(header[0])
+	length := binary.LittleEndian.Uint32(header[1:])
+	if length > maxFrameSize {
+		return 0, nil, fmt.Errorf("wire: frame length %d exceeds maximum of %d", length, maxFrameSize)
+	}
+
+	value = make([]byte, length)
+	if length > 0 {
+		if _, err = io.ReadFull(cr, value); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return frameType, value, nil
+}
+
+// WriteError writes an Error frame reporting status and an optional
+// human-readable message. It's used for both failure and success, replacing
+// the bare ack byte the legacy protocol used to send.
+func WriteError(ctx context.Context, writer io.Writer, status Status, message string) (err error) {
+	value := make([]byte, 2+len(message))
+	binary.LittleEndian.PutUint16(value, uint16(status))
+	copy(value[2:], message)
+
+	return WriteFrame(ctx, writer, FrameError, value)
+}
+
+// DecodeError splits an Error frame's value into its status and message.
+func DecodeError(value []byte) (status Status, message string, err error) {
+	if len(value) < 2 {
+		return 0, "", fmt.Errorf("wire: error frame too short")
+	}
+
+	status = Status(binary.LittleEndian.Uint16(value[:2]))
+	message = string(value[2:])
+
+	return status, message, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2023 Taylor Paddock
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package wire
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadHeader(t *testing.T) {
+	// Arrange
+	b := bytes.Buffer{}
+	expected := Header{Version: 1, Flags: 0}
+
+	// Act
+	err := WriteHeader(context.Background(), &b, expected)
+	require.NoError(t, err)
+	actual, err := ReadHeader(context.Background(), &b)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func TestReadHeaderBadMagic(t *testing.T) {
+	// Arrange
+	b := bytes.NewBuffer(make([]byte, headerSize))
+
+	// Act
+	_, err := ReadHeader(context.Background(), b)
+
+	// Assert
+	require.Error(t, err)
+}
+
+func TestNegotiate(t *testing.T) {
+	// Arrange
+	var tests = []struct {
+		name        string
+		peerVersion uint16
+		expected    uint16
+		expectErr   bool
+	}{
+		{name: "peer older", peerVersion: 1, expected: 1},
+		{name: "peer newer", peerVersion: 99, expected: Version},
+		{name: "peer unsupported", peerVersion: 0, expectErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			actual, err := Negotiate(test.peerVersion)
+
+			// Assert
+			if test.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestWriteReadFrame(t *testing.T) {
+	// Arrange
+	b := bytes.Buffer{}
+	expected := []byte("plot-k32-2023-01-01.plot")
+
+	// Act
+	err := WriteFrame(context.Background(), &b, FrameFileName, expected)
+	require.NoError(t, err)
+	frameType, value, err := ReadFrame(context.Background(), &b)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, FrameFileName, frameType)
+	require.Equal(t, expected, value)
+}
+
+func TestWriteReadError(t *testing.T) {
+	// Arrange
+	b := bytes.Buffer{}
+
+	// Act
+	err := WriteError(context.Background(), &b, StatusDigestMismatch, "digest mismatch")
+	require.NoError(t, err)
+	frameType, value, err := ReadFrame(context.Background(), &b)
+	require.NoError(t, err)
+	require.Equal(t, FrameError, frameType)
+
+	status, message, err := DecodeError(value)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, StatusDigestMismatch, status)
+	require.Equal(t, "digest mismatch", message)
+}